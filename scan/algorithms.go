@@ -0,0 +1,478 @@
+package scan
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// algorithmRegex matches the built-in algorithm names in any letter case;
+// canonicalName maps each match to its display name.
+var algorithmRegex = regexp.MustCompile(`(?i)\b(AES|RSA|DES|3DES|MD5|SHA-?([1-3]?\d\d?|4[0-8]?[0-9]|5[0-5]?[0-9]|6[0-4]?[0-9]|65[0-4]?)|Blowfish|RC[45]|ECC|Elliptic\sCurve|PGP|GPG|ChaCha20|Poly1305|HMAC|RC2|Camellia|Whirlpool|Salsa20|Twofish|Argon2|BCrypt|PBKDF2|Scrypt|DSA|Diffie-Hellman|ECDH|EdDSA|Curve25519|Curve448|GOST|SM2|SM3|SM4|ED25519)\b`)
+
+// keySizeRegex matches algorithms followed by a key or modulus size such as
+// "RSA 2048", "AES-128", "AES256" or "RSA/1024".
+var keySizeRegex = regexp.MustCompile(`(?i)\b(AES|RSA|DSA|Camellia|Blowfish|Twofish|RC[245]|ECC|ECDH|Diffie-Hellman)[ _/-]?(\d{2,5})\b`)
+
+// keySizes are the bit sizes accepted by keySizeRegex, which keeps version
+// numbers and years ("RSA 2019") from being read as key sizes.
+var keySizes = map[string]bool{
+	"40": true, "56": true, "64": true, "80": true, "112": true, "128": true,
+	"160": true, "168": true, "192": true, "224": true, "256": true, "384": true,
+	"512": true, "521": true, "768": true, "1024": true, "1536": true, "2048": true,
+	"3072": true, "4096": true, "7680": true, "8192": true, "15360": true, "16384": true,
+}
+
+// compoundRegex matches hyphenated names that denote a single construction,
+// such as "ChaCha20-Poly1305", "AES-256-GCM" or "SHA3-256".
+var compoundRegex = regexp.MustCompile(`(?i)\b(?:X?ChaCha20[-_]Poly1305|AES(?:[-_]?(?:128|192|256))?[-_](?:GCM[-_]SIV|GCM|CCM|SIV)|SHA3[-_]?(?:224|256|384|512))\b`)
+
+// protocolRegex matches SSL and TLS protocol versions in their common
+// spellings, such as "SSLv3", "TLS 1.0", "TLSv1.1", "TLS1_2", Python's
+// PROTOCOL_TLSv1_2 and Go's tls.VersionTLS12. Submatches 2-4 or 6-8 are the
+// protocol and its major and minor version; protocolName validates them.
+var protocolRegex = regexp.MustCompile(`(?i)(?:\b|_)((SSL|TLS)[ _]?v?(\d)(?:[._](\d))?)\b|(?-i)\bVersion((SSL|TLS)(\d)(\d))\b`)
+
+// protocolName returns the display name of a protocol version found by
+// protocolRegex, such as "SSL 3.0" or "TLS 1.2", or false if there is no
+// such version.
+func protocolName(protocol, major, minor string) (string, bool) {
+	switch protocol = strings.ToUpper(protocol); {
+	case protocol == "SSL" && (major == "2" || major == "3") && (minor == "" || minor == "0"):
+		return "SSL " + major + ".0", true
+	case protocol == "TLS" && major == "1" && minor <= "3":
+		if minor == "" {
+			minor = "0" // "TLSv1" is TLS 1.0
+		}
+		return "TLS 1." + minor, true
+	}
+	return "", false
+}
+
+// pemRegex matches the armor lines of a PEM block; submatch 1 is "BEGIN" or
+// "END" and submatch 2 is the block label.
+var pemRegex = regexp.MustCompile(`-----(BEGIN|END) ([A-Z0-9 ]+)-----`)
+
+// pemBlocks maps the PEM labels worth reporting to the name and severity
+// they are reported under. A committed private key is always weak, whatever
+// its algorithm; certificates and public keys are only inventoried.
+var pemBlocks = map[string]struct{ name, severity string }{
+	"RSA PRIVATE KEY":       {"RSA private key", "weak"},
+	"DSA PRIVATE KEY":       {"DSA private key", "weak"},
+	"EC PRIVATE KEY":        {"EC private key", "weak"},
+	"OPENSSH PRIVATE KEY":   {"OpenSSH private key", "weak"},
+	"PRIVATE KEY":           {"Private key", "weak"},
+	"ENCRYPTED PRIVATE KEY": {"Encrypted private key", "weak"},
+	"PGP PRIVATE KEY BLOCK": {"PGP private key", "weak"},
+	"CERTIFICATE":           {"Certificate", "ok"},
+	"TRUSTED CERTIFICATE":   {"Certificate", "ok"},
+	"PUBLIC KEY":            {"Public key", "ok"},
+	"RSA PUBLIC KEY":        {"RSA public key", "ok"},
+	"PGP PUBLIC KEY BLOCK":  {"PGP public key", "ok"},
+}
+
+// modeRegex matches block cipher modes of operation, including Python's
+// MODE_CBC constants and Go's cipher.NewCBCEncrypter style constructors.
+var modeRegex = regexp.MustCompile(`(?i)\b(?:MODE_)?(ECB|CBC|GCM|CTR|CFB|OFB)\b|(?-i)\bNew(ECB|CBC|GCM|CTR|CFB|OFB)`)
+
+// blockCiphers are the upper-case base names that a mode found on the same
+// line is attributed to.
+var blockCiphers = map[string]bool{
+	"3DES":     true,
+	"AES":      true,
+	"BLOWFISH": true,
+	"CAMELLIA": true,
+	"DES":      true,
+	"RC2":      true,
+	"RC5":      true,
+	"SM4":      true,
+	"TWOFISH":  true,
+}
+
+// modeSeverities raises the severity of a cipher used in an insecure mode.
+var modeSeverities = map[string]string{
+	"ECB": "weak",
+}
+
+var (
+	shaName     = regexp.MustCompile(`^SHA-?(\d+)$`)
+	sha3Name    = regexp.MustCompile(`^SHA3-?(\d+)$`)
+	aesSizeName = regexp.MustCompile(`^AES-?(\d+)-`)
+)
+
+// displayNames maps the upper-case form of each hyphen-separated part of a
+// built-in algorithm name to the way it is displayed. Parts not listed are
+// displayed in upper case.
+var displayNames = map[string]string{
+	"BCRYPT":         "BCrypt",
+	"BLOWFISH":       "Blowfish",
+	"CAMELLIA":       "Camellia",
+	"CHACHA20":       "ChaCha20",
+	"CURVE25519":     "Curve25519",
+	"CURVE448":       "Curve448",
+	"DIFFIE":         "Diffie",
+	"ED25519":        "Ed25519",
+	"EDDSA":          "EdDSA",
+	"ELLIPTIC CURVE": "Elliptic Curve",
+	"HELLMAN":        "Hellman",
+	"POLY1305":       "Poly1305",
+	"SALSA20":        "Salsa20",
+	"SCRYPT":         "Scrypt",
+	"TWOFISH":        "Twofish",
+	"WHIRLPOOL":      "Whirlpool",
+	"XCHACHA20":      "XChaCha20",
+}
+
+// canonicalName maps a matched name to a single display name regardless of
+// letter case and spelling, so that "sha1", "SHA1" and "SHA-1" or
+// "aes256_gcm" and "AES-256-GCM" are reported alike.
+func canonicalName(alg string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(strings.ReplaceAll(alg, "_", "-")), " "))
+	if m := sha3Name.FindStringSubmatch(name); m != nil {
+		return "SHA3-" + m[1]
+	}
+	if m := shaName.FindStringSubmatch(name); m != nil {
+		return "SHA-" + m[1]
+	}
+	parts := strings.Split(aesSizeName.ReplaceAllString(name, "AES-$1-"), "-")
+	for i, part := range parts {
+		if display, ok := displayNames[part]; ok {
+			parts[i] = display
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// splitAlgorithm returns the upper-case base algorithm of a reported name and
+// its key size, if any: "RSA-1024" gives ("RSA", 1024), "AES-256-GCM" gives
+// ("AES", 256) and "Diffie-Hellman" gives ("DIFFIE-HELLMAN", 0).
+func splitAlgorithm(alg string) (string, int) {
+	parts := strings.Split(strings.ToUpper(strings.Join(strings.Fields(alg), " ")), "-")
+	base, rest := parts[0], parts[1:]
+	if base == "DIFFIE" && len(rest) > 0 && rest[0] == "HELLMAN" {
+		base, rest = "DIFFIE-HELLMAN", rest[1:]
+	}
+	for _, part := range rest {
+		if bits, err := strconv.Atoi(part); err == nil {
+			return base, bits
+		}
+	}
+	return base, 0
+}
+
+// lowerSeverity returns the severity one level below severity, or severity
+// itself if it is already the lowest.
+func lowerSeverity(severity string) string {
+	for name, level := range severityLevels {
+		if level == severityLevels[severity]-1 {
+			return name
+		}
+	}
+	return severity
+}
+
+// groupSeverity returns the highest severity among matches of one algorithm,
+// which differ when some were down-ranked by nonSecurityUse.
+func groupSeverity(matches []Match) string {
+	severity := matches[0].Severity
+	for _, m := range matches[1:] {
+		if severityLevels[m.Severity] > severityLevels[severity] {
+			severity = m.Severity
+		}
+	}
+	return severity
+}
+
+// secretAssignRegex finds an identifier naming a key, IV or nonce (aes_key,
+// secretIV, "nonce":) assigned a literal that looks like key material: a
+// quoted hex or base64 string, escaped bytes, or a list of byte values.
+// Submatch 1 or 2 is the kind of secret and submatch 3 the literal.
+var secretAssignRegex = regexp.MustCompile(`(?:\b(?:\w*_)?(?i:(key|iv|nonce))|\b[a-z]\w*(Key|IV|Iv|Nonce))\b["']?\s*(?::=|=|:)\s*(?:new\s+byte\s*\[\s*\]\s*|\[\]byte\s*\(?\s*|b)?(["'](?:[0-9A-Za-z+/]{16,}={0,2}|(?:\\x[0-9A-Fa-f]{2}){8,})["']|[{\[]\s*(?:(?:0x)?[0-9A-Fa-f]{1,2}\s*,\s*){7,})`)
+
+// keyLiteralRegex finds literals long enough to be a 128-bit or larger key:
+// quoted hex or base64 strings and lists of at least 8 hex bytes.
+var keyLiteralRegex = regexp.MustCompile(`["'](?:0x)?[0-9A-Fa-f]{32,}["']|["'][A-Za-z0-9+/]{22,}={0,2}["']|(?:0x[0-9A-Fa-f]{1,2}\s*,\s*){7,}0x[0-9A-Fa-f]{1,2}`)
+
+// findConstants returns the literal keys and IVs on line. A literal assigned
+// to a key, IV or nonce variable is always reported; any other long literal
+// only when nearAlgorithm reports that the line also names an algorithm.
+// Literals without a digit are ignored as they are almost always words.
+func findConstants(line string, nearAlgorithm bool) []Finding {
+	var found []Finding
+	var spans [][]int
+	for _, sub := range secretAssignRegex.FindAllStringSubmatchIndex(line, -1) {
+		loc := sub[6:8]
+		if !strings.ContainsAny(line[loc[0]:loc[1]], "0123456789") {
+			continue
+		}
+		kind := "key"
+		if sub[2] >= 0 {
+			kind = line[sub[2]:sub[3]]
+		} else if sub[4] >= 0 {
+			kind = line[sub[4]:sub[5]]
+		}
+		name := "Hardcoded IV"
+		if strings.EqualFold(kind, "key") {
+			name = "Hardcoded key"
+		}
+		found = append(found, Finding{Algorithm: name, Severity: "weak", Start: loc[0], End: loc[1]})
+		spans = append(spans, loc)
+	}
+	if nearAlgorithm {
+		for _, loc := range keyLiteralRegex.FindAllStringIndex(line, -1) {
+			if strings.ContainsAny(line[loc[0]:loc[1]], "0123456789") && !overlaps(loc, spans) {
+				found = append(found, Finding{Algorithm: "Hardcoded key", Severity: "weak", Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+	return found
+}
+
+// categories classifies the built-in algorithms by what they do, keyed by
+// upper-case base name. SHA variants and protocol versions are handled by
+// categoryOf.
+var categories = map[string]string{
+	"3DES": "cipher", "AES": "cipher", "BLOWFISH": "cipher", "CAMELLIA": "cipher",
+	"CHACHA20": "cipher", "DES": "cipher", "GOST": "cipher", "RC2": "cipher",
+	"RC4": "cipher", "RC5": "cipher", "SALSA20": "cipher", "SM4": "cipher",
+	"TWOFISH": "cipher", "XCHACHA20": "cipher",
+
+	"MD5": "hash", "SM3": "hash", "WHIRLPOOL": "hash",
+
+	"HMAC": "mac", "POLY1305": "mac",
+
+	"ARGON2": "kdf", "BCRYPT": "kdf", "PBKDF2": "kdf", "SCRYPT": "kdf",
+
+	"DSA": "signature", "ED25519": "signature", "EDDSA": "signature",
+
+	"CURVE25519": "kex", "CURVE448": "kex", "DIFFIE-HELLMAN": "kex", "ECDH": "kex",
+
+	// Used for both encryption or key exchange and signatures
+	"ECC": "public-key", "ELLIPTIC CURVE": "public-key", "RSA": "public-key", "SM2": "public-key",
+
+	"GPG": "protocol", "PGP": "protocol",
+
+	"CBC": "mode", "CFB": "mode", "CTR": "mode", "ECB": "mode", "GCM": "mode", "OFB": "mode",
+
+	"HARDCODED IV": "key-material", "HARDCODED KEY": "key-material",
+}
+
+// categoryOf returns the category of a reported algorithm name: "cipher",
+// "hash", "mac", "kdf", "signature", "kex", "public-key", "protocol", "mode"
+// (a mode of operation found without a cipher),
+// "key-material" (PEM blocks, hardcoded keys and IVs, key files), "rng"
+// (insecure random number generators), "custom-crypto" (hand-rolled XOR
+// ciphers), "jwt" (JSON Web Token algorithms), or "other" for names it does
+// not know, such as -config patterns.
+func categoryOf(alg string) string {
+	base, _ := splitAlgorithm(alg)
+	switch {
+	case strings.HasPrefix(base, "SHA"):
+		return "hash"
+	case strings.HasPrefix(base, "SSL "), strings.HasPrefix(base, "TLS "):
+		return "protocol"
+	case strings.HasSuffix(base, " KEY"), base == "CERTIFICATE", base == keyFileAlgorithm:
+		return "key-material" // PEM blocks, hardcoded keys and key files
+	case strings.HasPrefix(base, "INSECURE RNG"):
+		return "rng"
+	case strings.HasPrefix(base, "XOR CIPHER"):
+		return "custom-crypto"
+	case strings.HasPrefix(base, "JWT "):
+		return "jwt"
+	}
+	if category, ok := categories[base]; ok {
+		return category
+	}
+	return "other"
+}
+
+// quantumRisk classifies algorithms by their exposure to a cryptographically
+// relevant quantum computer, independent of how strong they are classically.
+// Public-key schemes fall to Shor's algorithm; symmetric ciphers and hashes
+// only lose security margin to Grover's. Keys are upper case.
+var quantumRisk = map[string]string{
+	"RSA":            "vulnerable",
+	"DSA":            "vulnerable",
+	"ECC":            "vulnerable",
+	"ELLIPTIC CURVE": "vulnerable",
+	"DIFFIE-HELLMAN": "vulnerable",
+	"ECDH":           "vulnerable",
+	"EDDSA":          "vulnerable",
+	"ED25519":        "vulnerable",
+	"CURVE25519":     "vulnerable",
+	"CURVE448":       "vulnerable",
+	"SM2":            "vulnerable",
+	"AES":            "resistant",
+	"DES":            "resistant",
+	"3DES":           "resistant",
+	"MD5":            "resistant",
+	"BLOWFISH":       "resistant",
+	"RC2":            "resistant",
+	"RC4":            "resistant",
+	"RC5":            "resistant",
+	"CHACHA20":       "resistant",
+	"POLY1305":       "resistant",
+	"HMAC":           "resistant",
+	"CAMELLIA":       "resistant",
+	"WHIRLPOOL":      "resistant",
+	"SALSA20":        "resistant",
+	"TWOFISH":        "resistant",
+	"ARGON2":         "resistant",
+	"BCRYPT":         "resistant",
+	"PBKDF2":         "resistant",
+	"SCRYPT":         "resistant",
+	"SM3":            "resistant",
+	"SM4":            "resistant",
+}
+
+// fipsStatus classifies algorithms against FIPS 140-2, keyed by upper-case
+// base name. "disallowed" algorithms are broken or explicitly prohibited by
+// NIST SP 800-131A and SP 800-52; "non-approved" ones are simply outside the
+// approved set. SHA variants and undersized keys are handled by fipsStatusOf.
+var fipsStatus = map[string]string{
+	"AES": "approved", "DIFFIE-HELLMAN": "approved", "DSA": "approved",
+	"ECC": "approved", "ECDH": "approved", "ELLIPTIC CURVE": "approved",
+	"HMAC": "approved", "PBKDF2": "approved", "RSA": "approved",
+	"TLS 1.2": "approved", "TLS 1.3": "approved",
+
+	"ARGON2": "non-approved", "BCRYPT": "non-approved", "BLOWFISH": "non-approved",
+	"CAMELLIA": "non-approved", "CHACHA20": "non-approved", "CURVE25519": "non-approved",
+	"CURVE448": "non-approved", "ED25519": "non-approved", "EDDSA": "non-approved",
+	"GOST": "non-approved", "POLY1305": "non-approved", "SALSA20": "non-approved",
+	"SCRYPT": "non-approved", "SM2": "non-approved", "SM3": "non-approved",
+	"SM4": "non-approved", "TWOFISH": "non-approved", "WHIRLPOOL": "non-approved",
+	"XCHACHA20": "non-approved",
+
+	"3DES": "disallowed", "DES": "disallowed", "MD5": "disallowed", "RC2": "disallowed",
+	"RC4": "disallowed", "RC5": "disallowed", "SSL 2.0": "disallowed",
+	"SSL 3.0": "disallowed", "TLS 1.0": "disallowed", "TLS 1.1": "disallowed",
+}
+
+// fipsStatusOf returns "approved", "non-approved", "disallowed" or "n-a" for
+// a matched algorithm name. SHA-1 counts as disallowed since its remaining
+// approved uses exclude digital signatures, and approved algorithms with a
+// key below minimumKeySizes are disallowed.
+func fipsStatusOf(alg string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(alg), " "))
+	if strings.HasPrefix(name, "SHA") {
+		if strings.TrimPrefix(strings.TrimPrefix(name, "SHA"), "-") == "1" {
+			return "disallowed"
+		}
+		return "approved"
+	}
+	base, bits := splitAlgorithm(name)
+	if minimum, ok := minimumKeySizes[base]; ok && bits > 0 && bits < minimum {
+		return "disallowed"
+	}
+	if status, ok := fipsStatus[base]; ok {
+		return status
+	}
+	return "n-a"
+}
+
+// fipsViolations returns the algorithms in byAlgorithm that are not FIPS
+// approved, disallowed ones first and then by name.
+func fipsViolations(byAlgorithm map[string][]Match) []string {
+	var algs []string
+	for alg := range byAlgorithm {
+		if status := fipsStatusOf(alg); status == "non-approved" || status == "disallowed" {
+			algs = append(algs, alg)
+		}
+	}
+	sort.Slice(algs, func(i, j int) bool {
+		di, dj := fipsStatusOf(algs[i]) == "disallowed", fipsStatusOf(algs[j]) == "disallowed"
+		if di != dj {
+			return di
+		}
+		return algs[i] < algs[j]
+	})
+	return algs
+}
+
+// quantumRiskOf returns "vulnerable", "resistant" or "n-a" for a matched
+// algorithm name.
+func quantumRiskOf(alg string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(alg), " "))
+	if strings.HasPrefix(name, "SHA") {
+		return "resistant"
+	}
+	base, _ := splitAlgorithm(name)
+	if risk, ok := quantumRisk[base]; ok {
+		return risk
+	}
+	return "n-a"
+}
+
+// severityLevels orders the severity classifications from least to most
+// severe.
+var severityLevels = map[string]int{
+	"ok":         0,
+	"deprecated": 1,
+	"weak":       2,
+}
+
+// severities classifies algorithms that are not "ok". Keys are upper case;
+// sized names are classified by their base algorithm unless the size itself
+// is too small, see severityOf.
+var severities = map[string]string{
+	"MD5":      "weak",
+	"DES":      "weak",
+	"RC2":      "weak",
+	"RC4":      "weak",
+	"RC5":      "weak",
+	"3DES":     "deprecated",
+	"BLOWFISH": "deprecated",
+	"DSA":      "deprecated",
+	"GOST":     "deprecated",
+	"SSL 2.0":  "weak",
+	"SSL 3.0":  "weak",
+	"TLS 1.0":  "weak",
+	"TLS 1.1":  "weak",
+}
+
+// minimumKeySizes are the smallest acceptable sizes for sized algorithms.
+var minimumKeySizes = map[string]int{
+	"RSA":            2048,
+	"DSA":            2048,
+	"DIFFIE-HELLMAN": 2048,
+}
+
+// severityOf returns "ok", "deprecated" or "weak" for a matched algorithm
+// name.
+func severityOf(alg string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(alg), " "))
+	if strings.HasPrefix(name, "SHA") {
+		if strings.TrimPrefix(strings.TrimPrefix(name, "SHA"), "-") == "1" {
+			return "weak"
+		}
+		return "ok"
+	}
+	base, bits := splitAlgorithm(name)
+	if minimum, ok := minimumKeySizes[base]; ok && bits > 0 && bits < minimum {
+		return "weak"
+	}
+	for _, part := range strings.Split(name, "-") {
+		if severity, ok := modeSeverities[part]; ok {
+			return severity
+		}
+	}
+	if severity, ok := severities[base]; ok {
+		return severity
+	}
+	return "ok"
+}
+
+const ecbRemediation = "ECB encrypts identical plaintext blocks to identical ciphertext blocks and leaks data patterns; use an authenticated mode such as GCM instead."
+
+// hasMode reports whether a reported algorithm name already names a mode of
+// operation.
+func hasMode(alg string) bool {
+	for _, part := range strings.Split(strings.ToUpper(alg), "-") {
+		switch part {
+		case "ECB", "CBC", "GCM", "CTR", "CFB", "OFB", "CCM", "SIV":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package scan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// archiveSeparator joins an archive named on the command line and the path
+// of one of its entries in reported paths, as in "drop.zip!src/main.go".
+const archiveSeparator = "!"
+
+// archiveFormat returns "zip" or "tar" if the file name is an archive whose
+// entries are scanned when it is named on the command line, or "".
+func archiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar"), strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar"
+	}
+	return ""
+}
+
+// scanArchive scans the entries of the archive named on the command line as
+// name, whose resolved path is path, without extracting it. Entries are
+// reported under the archive, see archiveSeparator.
+func (s *scanner) scanArchive(name, path string) {
+	root := s.displayPath(name) + archiveSeparator
+	s.addRoot(rootKey(root, ""))
+	var err error
+	if archiveFormat(path) == "zip" {
+		err = s.scanZip(path, root)
+	} else {
+		var file *os.File
+		if file, err = os.Open(path); err == nil {
+			err = s.scanTar(file, root)
+			file.Close()
+		}
+	}
+	if err != nil {
+		s.errorf(name, "reading archive %s: %w", name, err)
+	}
+}
+
+// scanZip scans the regular files of the zip archive at name, recording
+// matches under root.
+func (s *scanner) scanZip(name, root string) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if s.stopped.Load() {
+			break
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		entry, err := f.Open()
+		if err != nil {
+			display := Match{Root: root, File: f.Name}.Path()
+			s.fileError(display, fmt.Errorf("opening %s: %w", display, err))
+			continue
+		}
+		s.scanEntry(root, path.Clean(f.Name), int64(f.UncompressedSize64), entry)
+		entry.Close()
+	}
+	return nil
+}
+
+// scanTar scans the regular files of a tar archive, gzip-compressed or not,
+// recording matches under root. Directory entries, symlinks and other
+// special entries carry no content and are skipped.
+func (s *scanner) scanTar(r io.Reader, root string) error {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+
+	tr := tar.NewReader(r)
+	for !s.stopped.Load() {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		s.scanEntry(root, path.Clean(strings.TrimPrefix(hdr.Name, "./")), hdr.Size, tr)
+	}
+	return nil
+}
+
+// scanEntry scans the archive entry name, of size bytes, read from r,
+// applying the same path, extension and binary filters used when walking a
+// directory. Ignore files do not apply, as there is no working tree.
+func (s *scanner) scanEntry(root, name string, size int64, r io.Reader) {
+	display := Match{Root: root, File: name}.Path()
+	reason := ""
+	switch {
+	case strings.HasPrefix(name, ".git/") || strings.Contains(name, "/.git/"):
+		reason = "git metadata"
+	case s.excluded(name):
+		reason = "-exclude"
+	case !s.included(name):
+		reason = "no -include match"
+	case s.isKeyFile(name), s.forceIncluded(name, false):
+	case !s.hasValidExtension(name):
+		reason = "extension"
+	case s.skipDocs && isDoc(s.innerName(name)):
+		reason = "documentation"
+	}
+	if reason != "" {
+		s.verbosef("Skipping %s: %s\n", display, reason)
+		return
+	}
+	if s.tooLarge(display, size) {
+		return
+	}
+
+	reader := bufio.NewReaderSize(r, max(s.sniffBytes, 4096))
+	var content io.Reader = reader
+	head, _ := reader.Peek(s.sniffBytes)
+	switch {
+	case s.scanBinaries:
+		content = binaryStrings(reader, s.sniffBytes)
+	case s.isKeyFile(name) && (!s.hasValidExtension(name) || isBinaryContent(head)):
+		content = strings.NewReader("") // Only the file's presence is reported
+	case isBinaryContent(head):
+		s.verbosef("Skipping %s: binary\n", display)
+		return
+	}
+	if s.listFiles {
+		fmt.Println(display)
+		return
+	}
+	s.verbosef("Scanning %s\n", display)
+	ctx, cancel := s.fileContext()
+	defer cancel()
+	if err := s.scanReader(ctx, content, root, name, size); err != nil {
+		s.fileError(display, err)
+	}
+}
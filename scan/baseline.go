@@ -0,0 +1,140 @@
+package scan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// baselineFile is the JSON document written by -write-baseline and read by
+// -baseline. Only fingerprints are compared; the other fields are there for
+// reviewers of the file.
+type baselineFile struct {
+	Version  int             `json:"version"`
+	Findings []baselineEntry `json:"findings"`
+}
+
+type baselineEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	Algorithm   string `json:"algorithm"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+}
+
+// baselineVersion is the current baselineFile format.
+const baselineVersion = 1
+
+// fingerprint identifies a match across runs by its algorithm, the path of
+// its file within the scan root and the text of its line, without the line
+// number or indentation, so that edits elsewhere in the file and reformatting
+// do not make an existing match look new.
+func fingerprint(alg, file, line string) string {
+	sum := sha256.Sum256([]byte(alg + "\x00" + file + "\x00" + strings.TrimSpace(line)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// readBaseline returns the entries of the baseline file name.
+func readBaseline(name string) ([]baselineEntry, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var baseline baselineFile
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, err
+	}
+	return baseline.Findings, nil
+}
+
+// loadBaseline reads a -baseline file and returns how many matches of each
+// fingerprint it holds.
+func loadBaseline(name string) (map[string]int, error) {
+	entries, err := readBaseline(name)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		counts[entry.Fingerprint]++
+	}
+	return counts, nil
+}
+
+// writeBaseline writes entries to the file name as a baseline, sorted so
+// that rewriting an unchanged baseline leaves the file unchanged. The file is
+// written under a temporary name and renamed into place, so an interrupted
+// write never leaves a truncated baseline behind.
+func writeBaseline(name string, entries []baselineEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Algorithm < b.Algorithm
+	})
+	data, err := json.MarshalIndent(baselineFile{Version: baselineVersion, Findings: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(name, append(data, '\n'), 0o644)
+}
+
+// replaceFile writes data to the file name with permissions perm through a
+// temporary file in the same directory renamed over it, so that readers see
+// either the old content or the new, never a partial write.
+func replaceFile(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// applyBaseline records matches for -write-baseline and drops the matches
+// already in the -baseline file. A fingerprint that occurs more often than
+// the baseline holds reports the extra occurrences as new. It is called with
+// s.mu held, once per scanned file.
+func (s *scanner) applyBaseline(matches []Match) []Match {
+	if s.writeBaseline {
+		for _, m := range matches {
+			s.baselineEntries = append(s.baselineEntries, baselineEntry{
+				Fingerprint: m.fingerprint,
+				Algorithm:   m.Algorithm,
+				File:        m.File,
+				Line:        m.Line,
+			})
+		}
+	}
+	if s.baseline == nil {
+		return matches
+	}
+	var fresh []Match
+	for _, m := range matches {
+		if s.baseline[m.fingerprint] > 0 {
+			s.baseline[m.fingerprint]--
+			continue
+		}
+		fresh = append(fresh, m)
+	}
+	return fresh
+}
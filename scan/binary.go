@@ -0,0 +1,326 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// defaultSniffBytes is the -sniff-bytes default, the length
+// http.DetectContentType considers.
+const defaultSniffBytes = 512
+
+// isBinaryFile reports whether the first n bytes of the file at filepath are
+// binary content. A file that cannot be opened or read is not known to be
+// binary, so it is left to the scan, which reports the error; nor is one that
+// is not a regular file, which opening could block on.
+func isBinaryFile(filepath string, n int) bool {
+	if info, err := os.Stat(filepath); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	file, err := os.Open(filepath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	// Short and empty files are text as far as their content goes
+	buffer := make([]byte, n)
+	n, err = io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false
+	}
+
+	return isBinaryContent(buffer[:n])
+}
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressed reports whether the file name is decompressed before scanning,
+// which -decompress enables for .gz files.
+func (s *scanner) decompressed(name string) bool {
+	return s.decompress && strings.EqualFold(filepath.Ext(name), ".gz")
+}
+
+// innerName returns the name whose extension describes the content of the
+// file name, "dump.sql" for a decompressed "dump.sql.gz".
+func (s *scanner) innerName(name string) string {
+	if s.decompressed(name) {
+		return name[:len(name)-len(".gz")]
+	}
+	return name
+}
+
+// readCloser pairs a reader wrapping a file with the file to close.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openFile opens the file at path for scanning. A decompressed file that
+// starts with the gzip magic bytes is read through gzip.NewReader; any other
+// file is read as is.
+func (s *scanner) openFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !s.decompressed(path) {
+		return file, nil
+	}
+	reader := bufio.NewReader(file)
+	if magic, _ := reader.Peek(len(gzipMagic)); !bytes.Equal(magic, gzipMagic) {
+		return readCloser{reader, file}, nil
+	}
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return readCloser{gz, file}, nil
+}
+
+// isBinary is isBinaryFile for the content that is actually scanned, so a
+// decompressed file is judged by its decompressed text.
+func (s *scanner) isBinary(path string) bool {
+	if !s.decompressed(path) {
+		return isBinaryFile(path, s.sniffBytes)
+	}
+	reader, err := s.openFile(path)
+	if err != nil {
+		return false // Reported by the scan, as in isBinaryFile
+	}
+	defer reader.Close()
+
+	buffer := make([]byte, s.sniffBytes)
+	n, err := io.ReadFull(reader, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	return isBinaryContent(buffer[:n])
+}
+
+// isBinaryContent reports whether the sniffed leading bytes of a file look
+// like anything other than text.
+func isBinaryContent(buffer []byte) bool {
+	// UTF-16 text, common on Windows, is full of NUL bytes; scanReader
+	// decodes it, see utf16Text
+	if utf16Order(buffer) != nil {
+		return false
+	}
+	// DetectContentType only looks at the first 512 bytes, so a larger
+	// -sniff-bytes sample must also be free of NUL bytes past them
+	contentType := http.DetectContentType(buffer)
+	if strings.HasPrefix(contentType, "text/") && bytes.IndexByte(buffer, 0) == -1 {
+		return false
+	}
+	// A PDF often opens with plain ASCII objects before its first compressed
+	// stream, but it is never worth scanning as text
+	if contentType == "application/pdf" {
+		return true
+	}
+	// DetectContentType rejects text containing sparse control characters
+	// such as vertical tabs, so valid UTF-8 is given the benefit of the doubt,
+	// and so is mostly printable text in a legacy encoding such as Latin-1,
+	// whatever format its leading bytes happen to suggest: "BM" is all it
+	// takes to be sniffed as image/bmp. Images and archives fail the ratio
+	return !isUTF8Text(buffer) && !mostlyPrintable(buffer)
+}
+
+// printableRatio is the share of printable ASCII and whitespace bytes from
+// which mostlyPrintable takes a sample for text.
+const printableRatio = 0.9
+
+// mostlyPrintable reports whether buffer is free of NUL bytes and made at
+// least printableRatio of printable ASCII and whitespace. Text in a
+// single-byte encoding passes, with its occasional accented letter or
+// control character; compressed or random data, where a byte is as likely
+// to be one value as any other, does not.
+func mostlyPrintable(buffer []byte) bool {
+	if len(buffer) == 0 || bytes.IndexByte(buffer, 0) != -1 {
+		return false
+	}
+	printable := 0
+	for _, b := range buffer {
+		if b >= ' ' && b < 0x7f || b == '\t' || b == '\n' || b == '\r' || b == '\f' {
+			printable++
+		}
+	}
+	return float64(printable) >= printableRatio*float64(len(buffer))
+}
+
+// isUTF8Text reports whether buffer is valid UTF-8 free of NUL bytes. A
+// multi-byte rune cut off at the end of the sample is not held against it.
+func isUTF8Text(buffer []byte) bool {
+	if len(buffer) == 0 || bytes.IndexByte(buffer, 0) != -1 {
+		return false
+	}
+	start := len(buffer) - 1
+	for start > 0 && len(buffer)-start < utf8.UTFMax && !utf8.RuneStart(buffer[start]) {
+		start--
+	}
+	if !utf8.FullRune(buffer[start:]) {
+		buffer = buffer[:start]
+	}
+	return utf8.Valid(buffer)
+}
+
+// minStringLength is the shortest run of printable characters extracted from
+// a binary, the default of strings(1).
+const minStringLength = 4
+
+// binaryStrings returns r unchanged if its content is text. Otherwise it
+// returns a reader yielding the printable ASCII strings found in r, one per
+// line, so that line numbers in matches count extracted strings.
+func binaryStrings(r *bufio.Reader, sniffBytes int) io.Reader {
+	head, _ := r.Peek(sniffBytes)
+	if !isBinaryContent(head) {
+		return r
+	}
+	return &stringsReader{r: r}
+}
+
+// stringsReader extracts runs of at least minStringLength printable ASCII
+// characters from a binary stream, like strings(1).
+type stringsReader struct {
+	r   *bufio.Reader
+	run []byte // Printable characters since the last unprintable byte
+	out []byte // Extracted strings not yet returned by Read
+}
+
+func (sr *stringsReader) Read(p []byte) (int, error) {
+	for len(sr.out) == 0 {
+		c, err := sr.r.ReadByte()
+		if err == nil && (c >= 0x20 && c < 0x7f || c == '\t') {
+			sr.run = append(sr.run, c)
+			continue
+		}
+		if len(sr.run) >= minStringLength {
+			sr.out = append(sr.run, '\n')
+		}
+		sr.run = nil
+		if err != nil && len(sr.out) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, sr.out)
+	sr.out = sr.out[n:]
+	return n, nil
+}
+
+// utf16Order returns the byte order given by the UTF-16 byte order mark that
+// head starts with, or nil if it starts with none.
+func utf16Order(head []byte) binary.ByteOrder {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return binary.LittleEndian
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// utf16Text returns a reader yielding the content of r decoded to UTF-8 if r
+// starts with a UTF-16 byte order mark, and r unchanged otherwise.
+func utf16Text(r *bufio.Reader) io.Reader {
+	bom, _ := r.Peek(2)
+	order := utf16Order(bom)
+	if order == nil {
+		return r
+	}
+	r.Discard(len(bom))
+	return &utf16Reader{r: r, order: order}
+}
+
+// utf16Reader decodes a UTF-16 stream without its byte order mark to UTF-8.
+// Unpaired surrogates decode to U+FFFD and a trailing odd byte is dropped.
+type utf16Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	out   []byte // Decoded text not yet returned by Read
+}
+
+func (ur *utf16Reader) Read(p []byte) (int, error) {
+	for len(ur.out) < len(p) {
+		unit, err := ur.r.Peek(2)
+		if len(unit) < 2 {
+			if len(ur.out) > 0 {
+				break
+			}
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		ur.r.Discard(2)
+		r := rune(ur.order.Uint16(unit))
+		if utf16.IsSurrogate(r) {
+			// The low half is only consumed if it really is one, so that a
+			// lone high surrogate does not swallow the next character
+			r2 := utf8.RuneError
+			if next, _ := ur.r.Peek(2); len(next) == 2 {
+				r2 = rune(ur.order.Uint16(next))
+			}
+			if decoded := utf16.DecodeRune(r, r2); decoded != utf8.RuneError {
+				ur.r.Discard(2)
+				r = decoded
+			} else {
+				r = utf8.RuneError
+			}
+		}
+		ur.out = utf8.AppendRune(ur.out, r)
+	}
+	n := copy(p, ur.out)
+	ur.out = ur.out[n:]
+	return n, nil
+}
+
+// notebook is the part of a Jupyter notebook (nbformat 4) that is scanned.
+type notebook struct {
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   json.RawMessage `json:"source"` // A string or a list of lines
+	} `json:"cells"`
+}
+
+// notebookCode returns the source of the code cells of the Jupyter notebook
+// read from r, one cell after another. Markdown and raw cells, outputs and
+// metadata are left out, so line numbers count lines of code across the
+// code cells rather than lines of the JSON file.
+func notebookCode(r io.Reader) (io.Reader, error) {
+	var nb notebook
+	if err := json.NewDecoder(r).Decode(&nb); err != nil {
+		return nil, err
+	}
+	var code strings.Builder
+	for _, cell := range nb.Cells {
+		if cell.CellType != "code" {
+			continue
+		}
+		var lines []string
+		if err := json.Unmarshal(cell.Source, &lines); err != nil {
+			var text string
+			if err := json.Unmarshal(cell.Source, &text); err != nil {
+				return nil, fmt.Errorf("code cell source: %w", err)
+			}
+			lines = []string{text}
+		}
+		source := strings.Join(lines, "")
+		code.WriteString(source)
+		if !strings.HasSuffix(source, "\n") {
+			code.WriteByte('\n')
+		}
+	}
+	return strings.NewReader(code.String()), nil
+}
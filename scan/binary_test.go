@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsBinaryFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		binary  bool
+	}{
+		{"empty.go", nil, false},
+		{"short.go", []byte("var k = 1"), false},
+		{"ten.txt", []byte("0123456789"), false},
+		{"nul.bin", []byte{'E', 'L', 'F', 0, 0, 0, 1, 2, 3, 4}, true},
+		{"image.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), true},
+		{"latin1.txt", []byte("caf\xe9 cr\xe8me uses AES\n"), false},
+		{"image.jpg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01\x01\x01\x00H\x00H\x00\x00"), true},
+		{"image.gif", []byte("GIF89a\x10\x00\x10\x00\x80\x00\x00\xff\xff\xff\x00\x00\x00!\xf9\x04"), true},
+		{"image.bmp", []byte("BM6\x00\x0c\x00\x00\x00\x00\x006\x00\x00\x00(\x00\x00\x00"), true},
+		{"doc.pdf", []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n"), true},
+		// Sniffed as image/bmp and application/postscript, but text all the same
+		{"cars.txt", []byte("BMW models, caf\xe9 edition: uses RC4\n"), false},
+		{"print.ps", []byte("%!PS-Adobe-3.0\n% r\xe9sum\xe9, MD5\n"), false},
+	}
+	dir := t.TempDir()
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, test.content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got := isBinaryFile(path, defaultSniffBytes); got != test.binary {
+			t.Errorf("isBinaryFile(%s) = %v, want %v", test.name, got, test.binary)
+		}
+		if got := isBinaryContent(test.content); got != test.binary {
+			t.Errorf("isBinaryContent(%s) = %v, want %v", test.name, got, test.binary)
+		}
+	}
+}
+
+func TestIsBinaryFileMissing(t *testing.T) {
+	if isBinaryFile(filepath.Join(t.TempDir(), "missing"), defaultSniffBytes) {
+		t.Error("isBinaryFile of a missing file = true, want false so the scan reports it")
+	}
+}
+
+func TestSniffBytes(t *testing.T) {
+	// Text whose binary payload starts past the default sniff length
+	content := []byte(strings.Repeat("// MD5 checksums below\n", 40))
+	content = append(content, make([]byte, 200)...)
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if isBinaryFile(path, defaultSniffBytes) {
+		t.Errorf("isBinaryFile(%d bytes) = true, want false", defaultSniffBytes)
+	}
+	if !isBinaryFile(path, 2048) {
+		t.Error("isBinaryFile(2048 bytes) = false, want true")
+	}
+}
+
+func TestPrintableRatio(t *testing.T) {
+	// With control characters, neither DetectContentType nor the UTF-8 check
+	// takes it for text; one byte in ten may be unprintable, but no more
+	text := []byte(strings.Repeat("abcdefghijklmnopqr\x01\xe9", 10))
+	if isBinaryContent(text) {
+		t.Errorf("isBinaryContent = true at %.0f%% printable", 100*printableRatio)
+	}
+	text = []byte(strings.Repeat("abcdefgh\x01\xe9", 10))
+	if !isBinaryContent(text) {
+		t.Error("isBinaryContent = false at 80% printable")
+	}
+	// A NUL byte is never text, however printable the rest
+	text = []byte(strings.Repeat("abcdefghij", 10) + "\xe9\x00")
+	if !isBinaryContent(text) {
+		t.Error("isBinaryContent = false with a NUL byte")
+	}
+}
@@ -0,0 +1,125 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blameLine is the commit that last modified a line, as reported by git blame.
+type blameLine struct {
+	author, commit string
+	date           string // Author date of the commit, in RFC 3339 format
+}
+
+// gitBlame runs git blame on the given lines of the file at path and returns
+// the last commit of each, keyed by line number. Lines not committed yet are
+// left out. It fails if git is not installed or the file is not tracked.
+func gitBlame(path string, lines []int) (map[int]blameLine, error) {
+	args := []string{"-C", filepath.Dir(path), "blame", "--line-porcelain"}
+	for _, n := range lines {
+		args = append(args, "-L", strconv.Itoa(n)+","+strconv.Itoa(n))
+	}
+	out, err := exec.Command("git", append(args, "--", filepath.Base(path))...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame(out)
+}
+
+// parseBlame reads the output of git blame --line-porcelain, keyed by final
+// line number. Lines not committed yet, blamed on the all-zero commit, are
+// left out.
+func parseBlame(out []byte) (map[int]blameLine, error) {
+	// Each line is described by a header "<commit> <orig line> <final line>",
+	// then "key value" pairs, then the line itself prefixed with a tab
+	blame := make(map[int]blameLine)
+	var commit, author, date string
+	var authorTime int64
+	line := 0
+	porcelain := bufio.NewScanner(bytes.NewReader(out))
+	porcelain.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for porcelain.Scan() {
+		text := porcelain.Text()
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			if strings.Trim(commit, "0") != "" {
+				blame[line] = blameLine{author: author, commit: commit, date: date}
+			}
+		case strings.HasPrefix(text, "author "):
+			author = strings.TrimPrefix(text, "author ")
+		case strings.HasPrefix(text, "author-time "):
+			authorTime, _ = strconv.ParseInt(strings.TrimPrefix(text, "author-time "), 10, 64)
+		case strings.HasPrefix(text, "author-tz "):
+			// Always follows author-time
+			date = time.Unix(authorTime, 0).In(blameZone(strings.TrimPrefix(text, "author-tz "))).Format(time.RFC3339)
+		default:
+			if fields := strings.Fields(text); len(fields) >= 3 && isObjectName(fields[0]) {
+				commit = fields[0]
+				line, _ = strconv.Atoi(fields[2])
+			}
+		}
+	}
+	return blame, porcelain.Err()
+}
+
+// isObjectName reports whether s is a full git object name: 40 hex digits
+// in a SHA-1 repository, 64 in a SHA-256 one.
+func isObjectName(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// blameZone returns the time zone of a git blame offset such as "+0130".
+func blameZone(tz string) *time.Location {
+	if len(tz) != 5 || (tz[0] != '+' && tz[0] != '-') {
+		return time.UTC
+	}
+	hours, err1 := strconv.Atoi(tz[1:3])
+	minutes, err2 := strconv.Atoi(tz[3:])
+	if err1 != nil || err2 != nil {
+		return time.UTC
+	}
+	offset := hours*3600 + minutes*60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(tz, offset)
+}
+
+// attachBlame fills in the author, commit and date of matches, all found in the
+// file at path, with -git-blame. Files that are not tracked by git keep
+// matches without an author.
+func (s *scanner) attachBlame(path string, matches []Match) {
+	var lines []int
+	seen := make(map[int]bool)
+	for _, m := range matches {
+		if !seen[m.Line] {
+			seen[m.Line] = true
+			lines = append(lines, m.Line)
+		}
+	}
+	blame, err := gitBlame(path, lines)
+	if err != nil {
+		s.verbosef("No git blame for %s: %s\n", path, err)
+		return
+	}
+	for i := range matches {
+		if b, ok := blame[matches[i].Line]; ok {
+			matches[i].Author = b.author
+			matches[i].Commit = b.commit
+			matches[i].Date = b.date
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitBlame(t *testing.T) {
+	testGitBlame(t, "sha1", 40)
+}
+
+func TestGitBlameSHA256(t *testing.T) {
+	testGitBlame(t, "sha256", 64)
+}
+
+// testGitBlame checks -git-blame against a temporary repository using the
+// given object format, whose commit names are hashLen hex digits long.
+func testGitBlame(t *testing.T, objectFormat string, hashLen int) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2023-04-05T10:00:00+0200", "GIT_COMMITTER_DATE=2023-04-05T10:00:00+0200")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package x\n// MD5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", dir, "init", "-q", "--object-format="+objectFormat).CombinedOutput(); err != nil {
+		t.Skipf("git cannot create a %s repository: %s", objectFormat, out)
+	}
+	git("add", "a.go")
+	git("-c", "user.name=Ada Lovelace", "-c", "user.email=ada@example.com", "commit", "-q", "-m", "init")
+	if err := os.WriteFile(path, []byte("package x\n// MD5\n// AES\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	matches := []Match{{Algorithm: "MD5", Line: 2}, {Algorithm: "AES", Line: 3}}
+	newTestScanner().attachBlame(path, matches)
+	if m := matches[0]; m.Author != "Ada Lovelace" || len(m.Commit) != hashLen || m.Date != "2023-04-05T10:00:00+02:00" {
+		t.Errorf("committed line blamed on %q, %q, %q", m.Author, m.Commit, m.Date)
+	}
+	if m := matches[1]; m.Author != "" || m.Commit != "" || m.Date != "" {
+		t.Errorf("uncommitted line blamed on %q, %q, %q", m.Author, m.Commit, m.Date)
+	}
+
+	outside := []Match{{Algorithm: "MD5", Line: 1}}
+	other := filepath.Join(t.TempDir(), "b.go")
+	if err := os.WriteFile(other, []byte("// MD5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	newTestScanner().attachBlame(other, outside)
+	if outside[0].Commit != "" {
+		t.Errorf("file outside git blamed on %q", outside[0].Commit)
+	}
+}
+
+func TestParseBlame(t *testing.T) {
+	sha256 := strings.Repeat("ab12", 16)
+	zero := strings.Repeat("0", 64)
+	out := sha256 + " 1 1 1\n" +
+		"author Ada Lovelace\nauthor-time 1680681600\nauthor-tz +0200\n" +
+		"summary init\nfilename a.go\n\tpackage x\n" +
+		zero + " 2 2 1\n" +
+		"author Not Committed Yet\nauthor-time 1680681600\nauthor-tz +0000\n" +
+		"filename a.go\n\t// MD5\n"
+	blame, err := parseBlame([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := blameLine{author: "Ada Lovelace", commit: sha256, date: "2023-04-05T10:00:00+02:00"}
+	if len(blame) != 1 || blame[1] != want {
+		t.Errorf("parseBlame = %+v, want line 1 only, %+v", blame, want)
+	}
+}
@@ -0,0 +1,83 @@
+package scan
+
+import "sync"
+
+// parallelFileSize is the size from which -threads-per-file shares out the
+// lines of a file; below it, the goroutines cost more than they save.
+const parallelFileSize = 8 << 20
+
+// chunkLines is the number of lines handed to a goroutine at once.
+const chunkLines = 2048
+
+// chunkResult holds what was found on one chunk of lines.
+type chunkResult struct {
+	matches []Match
+}
+
+type chunk struct {
+	lines  []scanLine
+	result *chunkResult
+}
+
+// chunkMatcher matches the lines of one file on several goroutines, for
+// -threads-per-file. The file is still read in order, where the comment state
+// carried from line to line is tracked, and only the detectors run in
+// parallel, so the matches are those found by a single goroutine, merged back
+// in line order.
+type chunkMatcher struct {
+	lm      *lineMatcher
+	chunks  chan chunk
+	pending []scanLine     // Lines read but not yet handed out
+	results []*chunkResult // One per chunk handed out, in line order
+	wg      sync.WaitGroup
+}
+
+// newChunkMatcher starts threads goroutines matching the lines added with
+// add. At most threads chunks wait to be matched, bounding the lines held in
+// memory.
+func newChunkMatcher(lm *lineMatcher, threads int) *chunkMatcher {
+	c := &chunkMatcher{lm: lm, chunks: make(chan chunk, threads)}
+	for i := 0; i < threads; i++ {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			for ch := range c.chunks {
+				r := ch.result
+				for _, l := range ch.lines {
+					r.matches = lm.match(l, r.matches)
+				}
+			}
+		}()
+	}
+	return c
+}
+
+// add queues l, handing out the pending lines once they fill a chunk.
+func (c *chunkMatcher) add(l scanLine) {
+	c.pending = append(c.pending, l)
+	if len(c.pending) == chunkLines {
+		c.flush()
+	}
+}
+
+func (c *chunkMatcher) flush() {
+	if len(c.pending) == 0 {
+		return
+	}
+	result := &chunkResult{}
+	c.results = append(c.results, result)
+	c.chunks <- chunk{lines: c.pending, result: result}
+	c.pending = nil
+}
+
+// wait matches the lines still pending, stops the goroutines and appends what
+// was found on every line to matches.
+func (c *chunkMatcher) wait(matches []Match) []Match {
+	c.flush()
+	close(c.chunks)
+	c.wg.Wait()
+	for _, r := range c.results {
+		matches = append(matches, r.matches...)
+	}
+	return matches
+}
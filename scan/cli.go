@@ -0,0 +1,596 @@
+package scan
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Exit codes returned by dumpvars.
+const (
+	exitOK       = 0 // Scan completed and no finding met -fail-on
+	exitError    = 1 // Invalid usage or an operational error such as an unreadable file
+	exitFindings = 2 // At least one finding at or above the -fail-on severity
+)
+
+// envPrefix starts the environment variables that configure dumpvars:
+// DUMPVARS_DIR and one per flag, such as DUMPVARS_FORMAT for -format.
+const envPrefix = "DUMPVARS_"
+
+// applyEnv sets each flag in fs whose DUMPVARS_ variable is present in the
+// environment. It runs before the command line is parsed, so flags given
+// there override the environment.
+func applyEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok || err != nil {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+		}
+	})
+	return err
+}
+
+// Main runs the dumpvars command with args, the command line without the
+// program name: it parses the flags, performs the scan and writes the report
+// to standard output, returning the process exit code.
+func Main(args []string) int {
+	started := time.Now()
+	if len(args) > 0 && args[0] == "validate-config" {
+		return validateConfig(args[1:])
+	}
+	argv := args
+	triageMode := len(argv) > 0 && argv[0] == "triage"
+	if triageMode {
+		argv = argv[1:]
+	}
+	s := &scanner{}
+	s.onError = s.printError
+	var listDetectors, detectJWT, tuiMode, detectCustomCrypto, detectWeakRNG, noProgress, tarMode, stdinMode, reportQuantum, jsonOutput, quiet, byFile, stats, detectConstants bool
+	var languageDecls stringList
+	var ignoreFileNames, configPath, allowPath, baselinePath, ignoreAlgos, nonSecurityHints, extraExt, onlyExt, severityMin, failOn, format string
+	flags := flag.NewFlagSet("dumpvars", flag.ContinueOnError)
+	flags.IntVar(&s.skipLinesOver, "skip-lines-over", 0, "skip matching on lines longer than `N` characters (0 disables)")
+	flags.IntVar(&s.lineWindow, "line-window", 1, "also match names split by string concatenation across up to `N` lines, such as \"Cha\" + and \"Cha20\" on the next line (1 disables)")
+	flags.BoolVar(&tarMode, "tar", false, "scan a tar archive, optionally gzip-compressed, read from stdin or from the file argument instead of a directory")
+	flags.BoolVar(&stdinMode, "stdin", false, "scan the files listed one per line on stdin instead of walking a directory")
+	flags.BoolVar(&s.fips, "fips", false, "summarize algorithms that are not FIPS 140-2 approved, separating non-approved from disallowed")
+	flags.BoolVar(&reportQuantum, "report-quantum", false, "inventory quantum-vulnerable algorithms for post-quantum migration planning")
+	flags.BoolVar(&jsonOutput, "json", false, "shorthand for -format json")
+	flags.StringVar(&format, "format", "text", "output `format`: text, table (one aligned row per algorithm), json, jsonl (one match per line, streamed), sarif, csv or html (a standalone page with context)")
+	flags.StringVar(&configPath, "config", "", "load additional algorithm patterns, and the groups of built-in extensions to scan or skip, from a JSON config `file`")
+	flags.StringVar(&allowPath, "allow", "", "drop reviewed matches listed in `file`, one \"ALGORITHM [GLOB[:LINE]]\" or \"contains TEXT\" per line")
+	flags.StringVar(&ignoreAlgos, "ignore-algo", "", "comma-separated `algorithms` to drop from the report whatever their severity, such as AES,SHA-256; a name also drops the more specific names it starts (AES drops AES-256-GCM)")
+	flags.StringVar(&nonSecurityHints, "non-security-hints", defaultNonSecurityHints, "comma-separated `words` that, as a word of an identifier on the same line as a hash, mark it as a likely non-security use, reported one severity lower (on by default; empty disables)")
+	flags.BoolVar(&s.hideNonSecurity, "hide-non-security", false, "drop hash matches marked by -non-security-hints instead of down-ranking them")
+	flags.BoolVar(&s.hints, "hints", false, "recommend a replacement for each weak or deprecated algorithm found")
+	flags.StringVar(&baselinePath, "baseline", "", "only report findings that are not in the baseline `file`, matched by algorithm, file and line text rather than line number")
+	flags.BoolVar(&s.writeBaseline, "write-baseline", false, "write every finding of this run to the -baseline file instead of reading it")
+	flags.StringVar(&extraExt, "ext", "", "comma-separated `extensions` to scan in addition to the built-in list")
+	flags.StringVar(&onlyExt, "only-ext", "", "comma-separated `extensions` to scan instead of the built-in list")
+	flags.BoolVar(&s.allExtensions, "all-ext", false, "scan files of any extension, relying on binary detection alone")
+	flags.IntVar(&s.workers, "workers", runtime.NumCPU(), "number of files to scan concurrently")
+	flags.IntVar(&s.parallelRoots, "parallel-roots", 4, "number of directory arguments to walk concurrently; with several, the report also summarizes each root")
+	flags.IntVar(&s.threadsPerFile, "threads-per-file", 1, "number of goroutines matching the lines of each file of 8 MB or more, for trees with a few very large files")
+	flags.StringVar(&severityMin, "severity-min", "ok", "only report algorithms at or above this `severity` (ok, deprecated, weak)")
+	flags.StringVar(&failOn, "fail-on", "", "exit with status 2 if any algorithm at or above this `severity` is found")
+	flags.BoolVar(&s.noGitIgnore, "no-gitignore", false, "scan files even if .gitignore or another of the -ignore-files excludes them")
+	flags.StringVar(&ignoreFileNames, "ignore-files", defaultIgnoreFiles, "comma-separated `names` of the ignore files read in every directory; .hgignore and .dockerignore are read in their own syntax, any other name, such as .npmignore, in .gitignore syntax")
+	flags.BoolVar(&s.withContext, "context", false, "print the matched source line under each finding")
+	flags.IntVar(&s.contextWidth, "context-width", defaultContextWidth(), "show at most `N` characters of context, centered on the match (0 for the whole line; defaults to the terminal width)")
+	flags.Var(&s.excludes, "exclude", "skip paths matching this `glob` (repeatable, ** matches any number of directories)")
+	flags.Var(&languageDecls, "lang", "scan files with extension EXT as LANGUAGE, a name such as php or python or another extension, for comment syntax and language-specific patterns (`EXT=LANGUAGE`, repeatable)")
+	flags.Var(&s.includes, "include", "only scan files matching this `glob` (repeatable); -exclude still applies")
+	flags.Var(&s.forceIncludeDirs, "force-include-dir", "scan every text file below directories matching this `glob` (repeatable), even if ignore files exclude them or their extension is not selected; -exclude and -include still apply")
+	flags.IntVar(&s.maxDepth, "depth", -1, "descend at most `N` directory levels below each root; 0 scans only the root's own files (-1 for no limit)")
+	flags.BoolVar(&s.followSymlinks, "follow-symlinks", false, "walk into symlinked directories, skipping any directory already visited")
+	s.maxSize = 10 << 20
+	flags.BoolVar(&detectConstants, "detect-constants", false, "report hardcoded keys and IVs: key/IV literals and long hex or base64 literals next to an algorithm (noisy)")
+	flags.BoolVar(&detectWeakRNG, "detect-weak-rng", false, "report non-cryptographic random number generators such as math/rand, Math.random and rand(), picked by file extension; weak when the line also names an algorithm")
+	flags.BoolVar(&s.detectKeyFiles, "detect-key-files", false, "report certificate, key and keystore files such as .pem, .key, .p12 and .jks as weak \"KEY FILE\" findings by their extension alone, whether or not they are text")
+	flags.BoolVar(&detectCustomCrypto, "detect-custom-crypto", false, "report hand-rolled XOR \"encryption\" as weak, such as XOR with a repeating key, with character codes or with a one-byte constant, in languages where ^ is XOR (heuristic: checksums and codecs can match too)")
+	flags.BoolVar(&detectJWT, "detect-jwt", false, "report the algorithms JSON Web Tokens are configured with in config and code: alg \"none\" as weak, and HS256, HS384 and HS512 as deprecated, worth reviewing the secret of")
+	flags.BoolVar(&listDetectors, "list-detectors", false, "list the detectors the other flags enable, with what each reports and an example, and exit; -format json lists them as JSON")
+	flags.IntVar(&s.sniffBytes, "sniff-bytes", defaultSniffBytes, "judge whether a file is binary by its first `N` bytes; raise it for text formats with binary-looking headers")
+	flags.BoolVar(&s.scanBinaries, "scan-binaries", false, "scan the printable strings of binary files of any extension, like strings(1), instead of skipping them")
+	flags.BoolVar(&s.decompress, "decompress", false, "scan the decompressed content of gzip files such as dump.sql.gz, filtered by their inner extension")
+	flags.DurationVar(&s.fileTimeout, "file-timeout", 0, "skip a file, reporting it as an error (exit status 1), if scanning it takes longer than `duration` such as 10s (0 disables)")
+	flags.BoolVar(&s.listFiles, "list-files", false, "print the files that would be scanned, one per line, without scanning them")
+	flags.BoolVar(&stats, "stats", false, "summarize the files and lines scanned per extension")
+	flags.BoolVar(&s.dedupContent, "dedup-content", false, "scan files with the same content only once, such as vendored copies or several links to one file; the copy reported is the one whose path sorts first")
+	flags.StringVar(&s.pathStyle, "path-style", "relative", "render reported paths as given on the command line (`relative`), as absolute paths (absolute) or relative to the working directory (cwd)")
+	flags.StringVar(&s.since, "since", "", "only scan the files of each directory changed between the git `ref` and HEAD, such as the last release tag; directories outside a git working tree are scanned in full, with a warning")
+	flags.BoolVar(&s.gitBlame, "git-blame", false, "attribute each finding to the author, commit and date of the commit that last changed its line, which introduced it as it stands, using git blame (not with -tar)")
+	flags.IntVar(&s.maxFindings, "max-findings", 0, "stop scanning once `N` findings at or above -severity-min are found and report only those, noting that the report is truncated (0 for no limit)")
+	flags.BoolVar(&s.allOccurrences, "all-occurrences", false, "report every occurrence of an algorithm on a line instead of only the first")
+	flags.BoolVar(&s.skipDocs, "skip-docs", false, "skip documentation files such as .md and .rst, whose matches are otherwise reported with source \"doc\"")
+	flags.BoolVar(&s.showClean, "show-clean", false, "list the files that were scanned and had no findings, to tell them apart from files never scanned")
+	flags.IntVar(&s.head, "head", 0, "list only the `N` most severe findings in the text and table reports, noting how many were omitted (0 for all)")
+	flags.IntVar(&s.tail, "tail", 0, "list only the `N` least severe findings in the text and table reports, noting how many were omitted (0 for all)")
+	flags.BoolVar(&tuiMode, "tui", false, "browse the findings interactively on the terminal once the scan is done, by severity and file, with their context and a live filter by algorithm")
+	flags.BoolVar(&byFile, "by-file", false, "group the text report by file instead of by algorithm")
+	flags.BoolVar(&quiet, "quiet", false, "write no report at all unless something at or above -severity-min is found, and no summary line to stderr")
+	flags.BoolVar(&s.skipComments, "skip-comments", false, "ignore matches inside comments, using the file extension to pick the comment syntax")
+	flags.BoolVar(&noProgress, "no-progress", false, "do not show the count of scanned files on stderr, which is otherwise shown when stderr is a terminal")
+	flags.BoolVar(&s.verbose, "verbose", false, "log each directory entered, file scanned and file skipped, with the reason, to stderr")
+	flags.Var(&s.memBudget, "mem-budget", "scan at most `size` bytes of files at once across all workers, with an optional K, M or G suffix; a larger file is scanned alone (0 disables)")
+	flags.Var(&s.maxSize, "max-size", "skip files larger than `size` bytes, with an optional K, M or G suffix (0 disables)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run main.go [flags] <source_code_directory|file|archive.zip|archive.tar.gz|git_url>...")
+		fmt.Fprintln(os.Stderr, "       go run main.go [flags] -tar [archive.tar|-]")
+		fmt.Fprintln(os.Stderr, "       git diff --name-only | go run main.go [flags] -stdin")
+		fmt.Fprintln(os.Stderr, "       go run main.go triage [flags] -baseline file <directory>...")
+		fmt.Fprintln(os.Stderr, "       go run main.go validate-config [config.json]")
+		flags.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "Path filters:")
+		fmt.Fprintln(os.Stderr, "  A file is scanned only if it matches no -exclude glob, matches at least one")
+		fmt.Fprintln(os.Stderr, "  -include glob when any are given, and passes the extension, binary and")
+		fmt.Fprintln(os.Stderr, "  ignore file checks. -exclude also prunes directories; -include does not. The")
+		fmt.Fprintln(os.Stderr, "  ignore files are .gitignore and .hgignore unless -ignore-files names others.")
+		fmt.Fprintln(os.Stderr, "  .dumpvarsignore uses .gitignore syntax for rules that only concern scanning,")
+		fmt.Fprintln(os.Stderr, "  and still applies with -no-gitignore.")
+		fmt.Fprintln(os.Stderr, "  -force-include-dir overrides ignore files and the extension list below the")
+		fmt.Fprintln(os.Stderr, "  directories it matches. A glob without a \"/\" only matches directories the")
+		fmt.Fprintln(os.Stderr, "  walk enters; to reach one inside an ignored directory, give its path, such as")
+		fmt.Fprintln(os.Stderr, "  build/generated or **/generated.")
+		fmt.Fprintln(os.Stderr, "  Symlinked files are filtered by their target's extension. Symlinked")
+		fmt.Fprintln(os.Stderr, "  directories are skipped unless -follow-symlinks is given. Files over -max-size")
+		fmt.Fprintln(os.Stderr, "  are skipped. -verbose names every skipped path and the check that failed.")
+		fmt.Fprintln(os.Stderr, "  -depth counts levels from the scan root whatever the other filters decide.")
+		fmt.Fprintln(os.Stderr, "  Directories beyond it are never entered, so their .gitignore files are not")
+		fmt.Fprintln(os.Stderr, "  read; within it, -exclude and ignore files prune as usual.")
+		fmt.Fprintln(os.Stderr, "Memory:")
+		fmt.Fprintln(os.Stderr, "  Files are read a line at a time, so a worker holds little more than its longest")
+		fmt.Fprintln(os.Stderr, "  line and the file's matches; notebooks are the exception and are read whole.")
+		fmt.Fprintln(os.Stderr, "  -workers bounds how many files are scanned at once; -mem-budget bounds their")
+		fmt.Fprintln(os.Stderr, "  total size on disk, so small files still keep every worker busy while a large")
+		fmt.Fprintln(os.Stderr, "  one waits until enough of the budget is free. A lower budget lowers the peak")
+		fmt.Fprintln(os.Stderr, "  at the cost of throughput on trees of large files.")
+		fmt.Fprintln(os.Stderr, "  -threads-per-file speeds up the other case, a few files far larger than the")
+		fmt.Fprintln(os.Stderr, "  rest: from 8 MB, a file is still read in order but its lines are matched by")
+		fmt.Fprintln(os.Stderr, "  several goroutines, each holding a chunk of lines, with the same results.")
+		fmt.Fprintln(os.Stderr, "Split names:")
+		fmt.Fprintln(os.Stderr, "  Files are matched a line at a time. With -line-window N, a line continuing the")
+		fmt.Fprintln(os.Stderr, "  string literal of the line before it, by + . .. & || or a trailing backslash,")
+		fmt.Fprintln(os.Stderr, "  is joined to up to N-1 such lines and the detectors run again over the result.")
+		fmt.Fprintln(os.Stderr, "  Only continued lines pay for this, but each of them is matched again with the")
+		fmt.Fprintln(os.Stderr, "  text of the whole window, so the worst case, a long run of concatenated")
+		fmt.Fprintln(os.Stderr, "  strings, costs about N times a plain scan; keep N small, 2 to 4 lines.")
+		fmt.Fprintln(os.Stderr, "Git URLs:")
+		fmt.Fprintln(os.Stderr, "  An argument such as https://host/owner/repo.git or git@host:owner/repo.git that")
+		fmt.Fprintln(os.Stderr, "  is not a local path is cloned with git clone --depth 1 to a temporary")
+		fmt.Fprintln(os.Stderr, "  directory, scanned, and removed. git handles authentication as usual, through")
+		fmt.Fprintln(os.Stderr, "  credential helpers, ssh keys and agents, or a prompt. Files are reported under")
+		fmt.Fprintln(os.Stderr, "  the URL. The clone has no history, so -git-blame does not apply to it, and with")
+		fmt.Fprintln(os.Stderr, "  -since it is scanned in full, with a warning.")
+		fmt.Fprintln(os.Stderr, "Non-security hints:")
+		fmt.Fprintln(os.Stderr, "  By default a hash on a line with an identifier word such as checksum, crc, etag,")
+		fmt.Fprintln(os.Stderr, "  cache, dedup or fingerprint (md5Checksum, cache_key) is reported one severity")
+		fmt.Fprintln(os.Stderr, "  lower, so an MD5 cache key is deprecated rather than weak and does not trip")
+		fmt.Fprintln(os.Stderr, "  -fail-on weak. -non-security-hints= turns this off; -hide-non-security drops")
+		fmt.Fprintln(os.Stderr, "  such matches instead.")
+		fmt.Fprintln(os.Stderr, "Triage:")
+		fmt.Fprintln(os.Stderr, "  triage scans as usual, then walks through each finding the -baseline file")
+		fmt.Fprintln(os.Stderr, "  does not hold yet, with its context, and asks what to do with it, one keypress")
+		fmt.Fprintln(os.Stderr, "  each: b adds it to the baseline, which is created if needed; i asks for a reason")
+		fmt.Fprintln(os.Stderr, "  and appends a dumpvars:ignore comment with it to the line in the file; s or")
+		fmt.Fprintln(os.Stderr, "  Enter skips it; a adds it and all remaining findings to the baseline; q, Ctrl-C")
+		fmt.Fprintln(os.Stderr, "  or Ctrl-D quits, keeping the answers given so far. Comments are only added to")
+		fmt.Fprintln(os.Stderr, "  files on disk in a language whose comment syntax is known, not inside archives")
+		fmt.Fprintln(os.Stderr, "  or compressed files. Files and the baseline are replaced in one rename, never")
+		fmt.Fprintln(os.Stderr, "  left half written.")
+		fmt.Fprintln(os.Stderr, "Inline suppression:")
+		fmt.Fprintln(os.Stderr, "  A line holding dumpvars:ignore in a comment reports nothing, such as")
+		fmt.Fprintln(os.Stderr, "    sum := md5.Sum(body) // dumpvars:ignore ETag, not a signature")
+		fmt.Fprintln(os.Stderr, "  Text after the marker is the reason. Inside strings the marker does not count;")
+		fmt.Fprintln(os.Stderr, "  in files whose comment syntax is unknown it counts anywhere on the line.")
+		fmt.Fprintln(os.Stderr, "Config:")
+		fmt.Fprintln(os.Stderr, "  validate-config checks a -config file, or the one DUMPVARS_CONFIG names,")
+		fmt.Fprintln(os.Stderr, "  without scanning: that it parses, has no unknown keys, names known extension")
+		fmt.Fprintln(os.Stderr, "  groups and severities, and that every pattern compiles. It lists every problem")
+		fmt.Fprintln(os.Stderr, "  found and exits with status 1 if there is any. To scan a directory named")
+		fmt.Fprintln(os.Stderr, "  validate-config, give it as ./validate-config.")
+		fmt.Fprintln(os.Stderr, "Environment:")
+		fmt.Fprintln(os.Stderr, "  DUMPVARS_DIR is scanned when no directory is given. Every flag can also be set")
+		fmt.Fprintln(os.Stderr, "  as DUMPVARS_<NAME>, such as DUMPVARS_FORMAT=json or DUMPVARS_SEVERITY_MIN=weak.")
+		fmt.Fprintln(os.Stderr, "  Flags on the command line override these; repeatable flags add to them.")
+		fmt.Fprintln(os.Stderr, "JSON output:")
+		fmt.Fprintln(os.Stderr, "  -format json and jsonl carry a schemaVersion, raised whenever a field is")
+		fmt.Fprintln(os.Stderr, "  removed or renamed or changes meaning. New fields may appear without a new")
+		fmt.Fprintln(os.Stderr, "  version, so consumers should ignore fields they do not know.")
+		fmt.Fprintln(os.Stderr, "Summary line:")
+		fmt.Fprintln(os.Stderr, "  Unless -quiet is given, every run ends with one line on stderr, whatever the")
+		fmt.Fprintln(os.Stderr, "  -format, with the same keys in the same order:")
+		fmt.Fprintln(os.Stderr, "    dumpvars: files=N findings=N weak=N deprecated=N ok=N errors=N elapsed=1.234s exit=N")
+		fmt.Fprintln(os.Stderr, "Exit status:")
+		fmt.Fprintln(os.Stderr, "  0  the scan completed and no finding met -fail-on")
+		fmt.Fprintln(os.Stderr, "  1  invalid usage, or an operational error such as an unreadable file or one")
+		fmt.Fprintln(os.Stderr, "     not scanned within -file-timeout")
+		fmt.Fprintln(os.Stderr, "  2  a finding met -fail-on (takes precedence over 1)")
+	}
+	if err := applyEnv(flags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+	if err := flags.Parse(argv); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitError
+	}
+
+	args = flags.Args()
+	if dir := os.Getenv(envPrefix + "DIR"); len(args) == 0 && !tarMode && !stdinMode && dir != "" {
+		args = []string{dir}
+	}
+	switch {
+	case s.since != "" && (tarMode || stdinMode):
+		fmt.Fprintln(os.Stderr, "Error: -since cannot be combined with -tar or -stdin")
+		return exitError
+	case tarMode && stdinMode,
+		tarMode && len(args) > 1,
+		stdinMode && len(args) > 0,
+		!tarMode && !stdinMode && len(args) == 0 && !listDetectors:
+		flags.Usage()
+		return exitError
+	}
+	if jsonOutput {
+		format = "json"
+	}
+	switch format {
+	case "text", "json", "jsonl", "sarif", "csv", "table":
+	case "html":
+		s.withContext = true // The page is meant to be read on its own
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", format)
+		return exitError
+	}
+	if triageMode {
+		switch {
+		case baselinePath == "" || s.writeBaseline:
+			fmt.Fprintln(os.Stderr, "Error: triage requires -baseline and cannot be combined with -write-baseline")
+			return exitError
+		case format != "text" || tuiMode:
+			fmt.Fprintln(os.Stderr, "Error: triage cannot be combined with -format or -tui")
+			return exitError
+		case stdinMode || !isTerminal(os.Stdin) || !isTerminal(os.Stdout):
+			fmt.Fprintln(os.Stderr, "Error: triage needs a terminal for input and output")
+			return exitError
+		}
+		s.withContext = true // Shown for each finding
+	}
+	if tuiMode {
+		switch {
+		case format != "text":
+			fmt.Fprintln(os.Stderr, "Error: -tui cannot be combined with -format")
+			return exitError
+		case stdinMode || !isTerminal(os.Stdin) || !isTerminal(os.Stdout):
+			fmt.Fprintln(os.Stderr, "Error: -tui needs a terminal for input and output")
+			return exitError
+		}
+		s.withContext = true // Shown for the selected finding
+	}
+	switch s.pathStyle {
+	case "relative", "absolute", "cwd":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown path style %q\n", s.pathStyle)
+		return exitError
+	}
+	if s.sniffBytes < 1 {
+		fmt.Fprintln(os.Stderr, "Error: -sniff-bytes must be at least 1")
+		return exitError
+	}
+	if s.head > 0 && s.tail > 0 {
+		fmt.Fprintln(os.Stderr, "Error: -head and -tail cannot be combined")
+		return exitError
+	}
+	minLevel, ok := severityLevels[severityMin]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown severity %q\n", severityMin)
+		return exitError
+	}
+	failLevel := len(severityLevels) // Above every level, so -fail-on is off by default
+	if failOn != "" {
+		if failLevel, ok = severityLevels[failOn]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown severity %q\n", failOn)
+			return exitError
+		}
+	}
+
+	if stats {
+		s.stats = make(map[string]*fileStats)
+	}
+	if tarMode {
+		s.gitBlame = false // Archive members are not in any working tree
+	}
+	if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		// -verbose and -list-files already show each file as it is reached
+		s.progress = !noProgress && !s.verbose && !s.listFiles
+	}
+	s.streamMin = minLevel
+	if format == "jsonl" {
+		s.stream = json.NewEncoder(os.Stdout)
+		s.streamed = make(map[string]bool)
+		s.written = make(map[string]int)
+	}
+
+	var cfg *config
+	if configPath != "" {
+		var err error
+		if cfg, err = loadConfig(configPath); err != nil {
+			printErrors("Error loading config", err)
+			return exitError
+		}
+	}
+
+	if onlyExt != "" {
+		s.extensions = make(map[string]bool)
+		for _, ext := range parseExtensions(onlyExt) {
+			s.extensions[ext] = true
+		}
+	} else {
+		s.extensions = make(map[string]bool, len(validExtensions))
+		for ext, group := range validExtensions {
+			if cfg == nil || cfg.Extensions.selects(group) {
+				s.extensions[ext] = true
+			}
+		}
+	}
+	for _, ext := range parseExtensions(extraExt) {
+		s.extensions[ext] = true
+	}
+	for _, name := range strings.Split(ignoreFileNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s.ignoreFiles = append(s.ignoreFiles, name)
+		}
+	}
+	s.languageOf = make(map[string]string)
+	for _, decl := range languageDecls {
+		ext, lang, err := parseLanguage(decl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return exitError
+		}
+		s.languageOf[ext] = lang
+		s.extensions[ext] = true // Declaring a language implies scanning it
+	}
+
+	s.detectors = []Detector{builtinDetector{}}
+	if cfg != nil {
+		s.detectors = append(s.detectors, patternDetector(cfg.Patterns))
+	}
+	if detectConstants {
+		s.detectors = append(s.detectors, constantDetector{})
+	}
+	if detectWeakRNG {
+		s.detectors = append(s.detectors, weakRNGDetector{})
+	}
+	if detectCustomCrypto {
+		s.detectors = append(s.detectors, customCryptoDetector{})
+	}
+	if detectJWT {
+		s.detectors = append(s.detectors, jwtDetector{})
+	}
+	if listDetectors {
+		if err := printDetectors(os.Stdout, describeDetectors(s.detectors), format == "json"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing detectors: %s\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+	for _, word := range strings.Split(nonSecurityHints, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			s.nonSecurityHints = append(s.nonSecurityHints, strings.ToLower(word))
+		}
+	}
+	for _, name := range strings.Split(ignoreAlgos, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s.ignoreAlgos = append(s.ignoreAlgos, strings.ToUpper(name))
+		}
+	}
+	if s.writeBaseline && baselinePath == "" {
+		fmt.Fprintln(os.Stderr, "Error: -write-baseline requires -baseline")
+		return exitError
+	}
+	if baselinePath != "" && !s.writeBaseline {
+		counts, err := loadBaseline(baselinePath)
+		if triageMode && errors.Is(err, fs.ErrNotExist) {
+			counts, err = make(map[string]int), nil // Triage starts the baseline
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading baseline: %s\n", err)
+			return exitError
+		}
+		s.baseline = counts
+	}
+	if allowPath != "" {
+		rules, err := loadAllowList(allowPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading allow list: %s\n", err)
+			return exitError
+		}
+		s.allow = rules
+	}
+
+	if tarMode {
+		// Read the archive from stdin unless a file is given
+		var archive io.Reader = os.Stdin
+		name := "-"
+		if len(args) == 1 && args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening tar archive: %s\n", err)
+				return exitError
+			}
+			defer file.Close()
+			archive, name = file, args[0]
+		}
+		if err := s.scanTar(archive, ""); err != nil {
+			s.errorf(name, "reading tar archive: %w", err)
+		}
+	} else if stdinMode {
+		s.startWorkers()
+		if err := s.scanFileList(os.Stdin); err != nil {
+			s.errorf("-", "reading file list: %w", err)
+		}
+		s.stopWorkers()
+	} else {
+		s.startWorkers()
+		s.scanRoots(args)
+		s.stopWorkers()
+		s.removeClones()
+	}
+
+	if s.writeBaseline && !s.listFiles {
+		if err := writeBaseline(baselinePath, s.baselineEntries); err != nil {
+			s.errorf(baselinePath, "writing baseline: %w", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Wrote %s to %s\n", plural(len(s.baselineEntries), "finding"), baselinePath)
+		}
+	}
+
+	if s.stopped.Load() {
+		fmt.Fprintf(os.Stderr, "Warning: stopped after %s (-max-findings); the report is incomplete\n", plural(s.maxFindings, "finding"))
+	}
+
+	code := exitOK
+	if s.failed {
+		code = exitError
+	}
+	if s.listFiles {
+		return code
+	}
+	for _, m := range s.matches {
+		if severityLevels[m.Severity] >= failLevel {
+			code = exitFindings
+			break
+		}
+	}
+	for severity := range s.streamed {
+		if severityLevels[severity] >= failLevel {
+			code = exitFindings
+		}
+	}
+	s.matches = filterSeverity(s.matches, minLevel)
+	sortMatches(s.matches)
+	sort.Strings(s.clean)
+	sort.Slice(s.scanErrors, func(i, j int) bool { return s.scanErrors[i].Path < s.scanErrors[j].Path })
+	if quiet && len(s.matches) == 0 {
+		return code
+	}
+
+	targets := args
+	if len(targets) == 0 {
+		targets = []string{"-"}
+	}
+	var err error
+	switch format {
+	case "json":
+		err = s.writeJSON(os.Stdout, targets)
+	case "sarif":
+		err = s.writeSARIF(os.Stdout)
+	case "csv":
+		err = s.writeCSV(os.Stdout)
+	case "table":
+		err = s.writeTable(os.Stdout)
+	case "html":
+		err = s.writeHTML(os.Stdout, targets)
+	case "jsonl":
+		err = s.streamErr // Matches were written as each file completed
+	default:
+		if triageMode {
+			err = s.triage(baselinePath, !tarMode)
+		} else if tuiMode {
+			err = s.runTUI()
+		} else {
+			s.printText(reportQuantum, byFile)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s output: %s\n", format, err)
+		return exitError
+	}
+	if !quiet && !s.listFiles {
+		fmt.Fprintln(os.Stderr, s.summaryLine(time.Since(started), code))
+	}
+	return code
+}
+
+// printError is the error handler of the command: it writes err to stderr
+// as an error, or as a warning if it is a *Warning, clearing the progress
+// line first. Its messages name path already.
+func (s *scanner) printError(path string, err error) {
+	if s.progress {
+		fmt.Fprint(os.Stderr, clearLine)
+	}
+	var warning *Warning
+	if errors.As(err, &warning) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning.Err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error %s\n", err)
+}
+
+// validateConfig runs the validate-config command: it loads the config file
+// named in args, or else by DUMPVARS_CONFIG, as -config would, and reports
+// every problem found without scanning anything. It returns exitError if
+// there is any.
+func validateConfig(args []string) int {
+	path := os.Getenv(envPrefix + "CONFIG")
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if len(args) > 1 || path == "" {
+		fmt.Fprintln(os.Stderr, "Usage: go run main.go validate-config [config.json]")
+		return exitError
+	}
+	if _, err := loadConfig(path); err != nil {
+		printErrors("Error loading config", err)
+		return exitError
+	}
+	fmt.Printf("%s: OK\n", path)
+	return exitOK
+}
+
+// printErrors writes each of the errors joined in err to stderr on a line of
+// its own, after prefix.
+func printErrors(prefix string, err error) {
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", prefix, line)
+	}
+}
+
+// printDetectors writes infos to w, as JSON with asJSON.
+func printDetectors(w io.Writer, infos []DetectorInfo, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	}
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s [%s, %s]\n", info.Name, info.Severity, info.Category)
+		if info.Description != "" {
+			fmt.Fprintf(w, "    %s\n", info.Description)
+		}
+		if info.Example != "" {
+			in := ""
+			if info.Extension != "" {
+				in = " (in " + info.Extension + ")"
+			}
+			fmt.Fprintf(w, "    Example%s: %s\n", in, info.Example)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package scan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// runTree is the tree the end-to-end tests scan, and runFound what is found
+// in it.
+var (
+	runTree = map[string]string{
+		"a.go":     "// MD5\nvar x = \"AES\"\n",
+		"sub/b.py": "# DES\n",
+	}
+	runFound = []string{"a.go:1 MD5", "a.go:2 AES", "sub/b.py:1 DES"}
+)
+
+// runArgs runs the command with args, as main does, and returns its exit
+// code and standard output.
+func runArgs(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	saved := os.Stdout
+	defer func() { os.Stdout = saved }()
+	os.Stdout = out
+
+	code := Main(args)
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(data)
+}
+
+// runStdin runs the command as runArgs does, with input on stdin.
+func runStdin(t *testing.T, input string, args ...string) (int, string) {
+	t.Helper()
+	in, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if _, err := in.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdin
+	defer func() { os.Stdin = saved }()
+	os.Stdin = in
+	return runArgs(t, args...)
+}
+
+// reported parses the -format csv report out and returns "file:line
+// algorithm" for each finding, with prefix trimmed from its file.
+func reported(t *testing.T, out, prefix string) []string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil || len(records) == 0 {
+		t.Fatalf("reading CSV report: %v\n%s", err, out)
+	}
+	var list []string
+	for _, record := range records[1:] {
+		list = append(list, strings.TrimPrefix(record[1], prefix)+":"+record[2]+" "+record[0])
+	}
+	return list
+}
+
+// prefixed returns list with prefix added to each element.
+func prefixed(prefix string, list []string) []string {
+	var out []string
+	for _, s := range list {
+		out = append(out, prefix+s)
+	}
+	return out
+}
+
+func TestRunFormats(t *testing.T) {
+	dir := writeTree(t, runTree)
+	root := dir + string(filepath.Separator)
+	tests := map[string]func(t *testing.T, out string){
+		"text": func(t *testing.T, out string) {
+			for _, want := range []string{
+				"- AES [ok, cipher]: 1 occurrence across 1 file\n    " + root + "a.go:2:10\n",
+				"- DES [weak, cipher]: 1 occurrence across 1 file\n    " + filepath.Join(dir, "sub", "b.py") + ":1:3\n",
+				"- MD5 [weak, hash]: 1 occurrence across 1 file\n    " + root + "a.go:1:4\n",
+			} {
+				if !strings.Contains(out, want) {
+					t.Errorf("report lacks %q:\n%s", want, out)
+				}
+			}
+		},
+		"table": func(t *testing.T, out string) {
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				got = append(got, strings.Join(strings.Fields(line), " "))
+			}
+			want := []string{
+				"ALGORITHM COUNT FILES SEVERITY CATEGORY",
+				"AES 1 1 ok cipher",
+				"DES 1 1 weak cipher",
+				"MD5 1 1 weak hash",
+			}
+			if !sameList(got, want) {
+				t.Errorf("rows %q, want %q", got, want)
+			}
+		},
+		"json": func(t *testing.T, out string) {
+			var report jsonReport
+			if err := json.Unmarshal([]byte(out), &report); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, alg := range report.Algorithms {
+				got = append(got, alg.Algorithm+" "+alg.Severity+" "+strconv.Itoa(alg.Count))
+			}
+			if want := []string{"AES ok 1", "DES weak 1", "MD5 weak 1"}; !sameList(got, want) {
+				t.Errorf("algorithms %q, want %q", got, want)
+			}
+			if report.SchemaVersion != jsonSchemaVersion || report.Summary.FilesScanned != 2 {
+				t.Errorf("schemaVersion %d, filesScanned %d, want %d and 2", report.SchemaVersion, report.Summary.FilesScanned, jsonSchemaVersion)
+			}
+		},
+		"jsonl": func(t *testing.T, out string) {
+			var got []string
+			decoder := json.NewDecoder(strings.NewReader(out))
+			for decoder.More() {
+				var m jsonMatch
+				if err := decoder.Decode(&m); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, strings.TrimPrefix(m.File, root)+":"+strconv.Itoa(m.Line)+" "+m.Algorithm)
+			}
+			if !sameList(got, runFound) {
+				t.Errorf("found %q, want %q", got, runFound)
+			}
+		},
+		"csv": func(t *testing.T, out string) {
+			if got := reported(t, out, root); !sameList(got, runFound) {
+				t.Errorf("found %q, want %q", got, runFound)
+			}
+		},
+		"sarif": func(t *testing.T, out string) {
+			var log sarifLog
+			if err := json.Unmarshal([]byte(out), &log); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range log.Runs[0].Results {
+				loc := r.Locations[0].PhysicalLocation
+				got = append(got, strings.TrimPrefix(loc.ArtifactLocation.URI, filepath.ToSlash(root))+":"+strconv.Itoa(loc.Region.StartLine)+" "+r.RuleID+" "+r.Level)
+			}
+			want := []string{"a.go:1 crypto/md5 error", "a.go:2 crypto/aes note", "sub/b.py:1 crypto/des error"}
+			if !sameList(got, want) {
+				t.Errorf("results %q, want %q", got, want)
+			}
+		},
+		"html": func(t *testing.T, out string) {
+			for _, want := range []string{
+				"<summary>weak: 2 findings in 2 files</summary>",
+				"<summary>ok: 1 finding in 1 file</summary>",
+				`<span class="where">1:4</span> <span class="alg">MD5</span>`,
+				"<pre>var x = &#34;AES&#34;</pre>",
+				"</html>",
+			} {
+				if !strings.Contains(out, want) {
+					t.Errorf("page lacks %q:\n%s", want, out)
+				}
+			}
+		},
+	}
+	for format, check := range tests {
+		t.Run(format, func(t *testing.T) {
+			code, out := runArgs(t, "-format", format, dir)
+			if code != exitOK {
+				t.Fatalf("exit %d, want %d:\n%s", code, exitOK, out)
+			}
+			check(t, out)
+		})
+	}
+}
+
+func TestRunDirectories(t *testing.T) {
+	parent := t.TempDir()
+	for _, name := range []string{"one", "two"} {
+		for path, content := range runTree {
+			path = filepath.Join(parent, name, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	code, out := runArgs(t, "-format", "csv", filepath.Join(parent, "one"), filepath.Join(parent, "two"))
+	want := append(prefixed("one/", runFound), prefixed("two/", runFound)...)
+	if got := reported(t, out, parent+"/"); code != exitOK || !sameList(got, want) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, want)
+	}
+}
+
+func TestRunSingleFile(t *testing.T) {
+	dir := writeTree(t, runTree)
+	code, out := runArgs(t, "-format", "csv", filepath.Join(dir, "a.go"))
+	want := []string{"a.go:1 MD5", "a.go:2 AES"}
+	if got := reported(t, out, dir+"/"); code != exitOK || !sameList(got, want) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, want)
+	}
+}
+
+func TestRunStdin(t *testing.T) {
+	dir := writeTree(t, runTree)
+	list := filepath.Join(dir, "a.go") + "\n" + filepath.Join(dir, "sub", "b.py") + "\n"
+	code, out := runStdin(t, list, "-format", "csv", "-stdin")
+	if got := reported(t, out, dir+"/"); code != exitOK || !sameList(got, runFound) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, runFound)
+	}
+}
+
+// tarArchive returns a tar archive of files, gzip-compressed with gz.
+func tarArchive(t *testing.T, files map[string]string, gz bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var gw *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if gz {
+		gw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gw)
+	}
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz {
+		if err := gw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// zipArchive returns a zip archive of files.
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRunTar(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"plain.tar": string(tarArchive(t, runTree, false)),
+	})
+	code, out := runArgs(t, "-format", "csv", "-tar", filepath.Join(dir, "plain.tar"))
+	if got := reported(t, out, ""); code != exitOK || !sameList(got, runFound) {
+		t.Errorf("file argument: exit %d, found %q, want 0 and %q", code, got, runFound)
+	}
+	code, out = runStdin(t, string(tarArchive(t, runTree, true)), "-format", "csv", "-tar")
+	if got := reported(t, out, ""); code != exitOK || !sameList(got, runFound) {
+		t.Errorf("gzip on stdin: exit %d, found %q, want 0 and %q", code, got, runFound)
+	}
+}
+
+func TestRunArchives(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"src.tar.gz": string(tarArchive(t, runTree, true)),
+		"src.zip":    string(zipArchive(t, runTree)),
+	})
+	code, out := runArgs(t, "-format", "csv", filepath.Join(dir, "src.tar.gz"), filepath.Join(dir, "src.zip"))
+	want := append(prefixed("src.tar.gz!", runFound), prefixed("src.zip!", runFound)...)
+	if got := reported(t, out, dir+"/"); code != exitOK || !sameList(got, want) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, want)
+	}
+}
+
+func TestRunClone(t *testing.T) {
+	dir := gitCommits(t, runTree)
+	code, out := runArgs(t, "-format", "csv", "file://"+dir)
+	if got := reported(t, out, "file://"+dir+"/"); code != exitOK || !sameList(got, runFound) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, runFound)
+	}
+}
+
+func TestRunDedupContent(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go":        runTree["a.go"],
+		"vendor/a.go": runTree["a.go"],
+		"sub/b.py":    runTree["sub/b.py"],
+	})
+	code, out := runArgs(t, "-format", "csv", "-dedup-content", dir)
+	if got := reported(t, out, dir+"/"); code != exitOK || !sameList(got, runFound) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, runFound)
+	}
+}
+
+func TestRunLineWindow(t *testing.T) {
+	dir := writeTree(t, map[string]string{"split.go": "x := \"Cha\" +\n\t\"Cha20\"\n"})
+	if code, out := runArgs(t, "-format", "csv", dir); code != exitOK || len(reported(t, out, "")) != 0 {
+		t.Errorf("exit %d without -line-window, want 0 and nothing found:\n%s", code, out)
+	}
+	code, out := runArgs(t, "-format", "csv", "-line-window", "2", dir)
+	want := []string{"split.go:1 ChaCha20"}
+	if got := reported(t, out, dir+"/"); code != exitOK || !sameList(got, want) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, want)
+	}
+}
+
+func TestRunThreadsPerFile(t *testing.T) {
+	var content strings.Builder
+	var want []string
+	// Blank lines are the fastest to match, keeping the test short
+	line := strings.Repeat(" ", 1023) + "\n"
+	for n := 1; content.Len() < parallelFileSize+len(line); n++ {
+		if n%1000 == 0 {
+			content.WriteString("h := md5.New()\n")
+			want = append(want, "big.go:"+strconv.Itoa(n)+" MD5")
+			continue
+		}
+		content.WriteString(line)
+	}
+	dir := writeTree(t, map[string]string{"big.go": content.String()})
+	code, out := runArgs(t, "-format", "csv", "-threads-per-file", "4", dir)
+	if got := reported(t, out, dir+"/"); code != exitOK || !sameList(got, want) {
+		t.Errorf("exit %d, found %q, want 0 and %q", code, got, want)
+	}
+}
@@ -0,0 +1,254 @@
+package scan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// commentSyntax describes how a language writes comments, for -skip-comments.
+type commentSyntax struct {
+	line       []string // Tokens starting a comment that runs to the end of the line
+	blockStart string   // Token opening a block comment, if the language has them
+	blockEnd   string
+}
+
+var (
+	cComments    = commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/"}
+	hashComments = commentSyntax{line: []string{"#"}}
+	dashComments = commentSyntax{line: []string{"--"}}
+)
+
+// commentSyntaxes maps file extensions to their comment syntax. Styling
+// files are left out since their comments already decide which short tokens
+// count, see styleRegions.
+var commentSyntaxes = map[string]commentSyntax{
+	".c": cComments, ".cc": cComments, ".cpp": cComments, ".cxx": cComments,
+	".h": cComments, ".hh": cComments, ".hpp": cComments, ".hxx": cComments,
+	".cs": cComments, ".cu": cComments, ".d": cComments, ".dart": cComments,
+	".go": cComments, ".groovy": cComments, ".java": cComments, ".js": cComments,
+	".jsx": cComments, ".kt": cComments, ".m": cComments, ".mm": cComments,
+	".proto": cComments, ".rs": cComments, ".scala": cComments, ".swift": cComments,
+	".ts": cComments, ".tsx": cComments, ".vala": cComments,
+
+	".php": {line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"},
+	".hcl": {line: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/"},
+	".sql": {line: []string{"--"}, blockStart: "/*", blockEnd: "*/"},
+
+	".awk": hashComments, ".cr": hashComments, ".ex": hashComments, ".exs": hashComments,
+	".fish": hashComments, ".jl": hashComments, ".nim": hashComments, ".pl": hashComments,
+	".pm": hashComments, ".ps1": hashComments, ".py": hashComments, ".pyi": hashComments, ".ipynb": hashComments,
+	".r": hashComments, ".rake": hashComments, ".rb": hashComments, ".sh": hashComments,
+	".tcl": hashComments, ".toml": hashComments, ".yaml": hashComments, ".yml": hashComments,
+	".zsh": hashComments,
+
+	".ada": dashComments, ".elm": dashComments, ".hs": dashComments, ".lua": dashComments,
+	".plsql": dashComments,
+}
+
+// languages maps the language names accepted by -lang to the extension whose
+// comment syntax and language-specific patterns they use.
+var languages = map[string]string{
+	"c": ".c", "c++": ".cpp", "cpp": ".cpp", "c#": ".cs", "csharp": ".cs",
+	"css": ".css", "dart": ".dart", "go": ".go", "groovy": ".groovy",
+	"haskell": ".hs", "hcl": ".hcl", "html": ".html", "java": ".java",
+	"javascript": ".js", "js": ".js", "jupyter": ".ipynb", "kotlin": ".kt",
+	"less": ".less", "lua": ".lua", "perl": ".pl", "php": ".php",
+	"powershell": ".ps1", "python": ".py", "r": ".r", "ruby": ".rb",
+	"rust": ".rs", "scala": ".scala", "scss": ".scss", "shell": ".sh",
+	"sql": ".sql", "swift": ".swift", "toml": ".toml", "typescript": ".ts",
+	"ts": ".ts", "xml": ".xml", "yaml": ".yaml",
+}
+
+// parseLanguage parses a -lang declaration such as "inc=php" or
+// ".tpl=.html" into the declared extension and the extension whose rules it
+// follows. The language is a name from languages or an extension.
+func parseLanguage(decl string) (string, string, error) {
+	ext, lang, ok := strings.Cut(decl, "=")
+	exts := parseExtensions(ext)
+	if !ok || len(exts) != 1 {
+		return "", "", fmt.Errorf("invalid -lang %q, want EXT=LANGUAGE", decl)
+	}
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if strings.HasPrefix(lang, ".") {
+		return exts[0], lang, nil
+	}
+	if langExt, ok := languages[lang]; ok {
+		return exts[0], langExt, nil
+	}
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", "", fmt.Errorf("unknown language %q in -lang, want an extension or one of %s", lang, strings.Join(names, ", "))
+}
+
+// languageExt returns the extension whose language rules apply to files
+// with extension ext: the one declared for it with -lang, or ext itself.
+func (s *scanner) languageExt(ext string) string {
+	if lang, ok := s.languageOf[ext]; ok {
+		return lang
+	}
+	return ext
+}
+
+// commentRegions returns the byte ranges of line that are comments in the
+// given syntax. Comment tokens inside quoted strings are ignored. inBlock
+// tells whether the line starts inside a block comment, and the returned
+// flag whether one is still open at the end of the line.
+func commentRegions(line string, syntax commentSyntax, inBlock bool) ([][2]int, bool) {
+	regions, inBlock, _ := lineComments(line, syntax, inBlock)
+	return regions, inBlock
+}
+
+// lineComments is commentRegions that also returns the quote character of a
+// string still open at the end of the line, or 0.
+func lineComments(line string, syntax commentSyntax, inBlock bool) ([][2]int, bool, byte) {
+	var regions [][2]int
+	var quote byte
+	start := 0
+	for i := 0; i < len(line); {
+		switch {
+		case inBlock:
+			j := strings.Index(line[i:], syntax.blockEnd)
+			if j == -1 {
+				return append(regions, [2]int{start, len(line)}), true, 0
+			}
+			i += j + len(syntax.blockEnd)
+			regions = append(regions, [2]int{start, i})
+			inBlock = false
+		case quote != 0:
+			if line[i] == '\\' {
+				i++
+			} else if line[i] == quote {
+				quote = 0
+			}
+			i++
+		case line[i] == '"' || line[i] == '\'' || line[i] == '`':
+			quote = line[i]
+			i++
+		case syntax.blockStart != "" && strings.HasPrefix(line[i:], syntax.blockStart):
+			start, inBlock = i, true
+			i += len(syntax.blockStart)
+		default:
+			for _, token := range syntax.line {
+				if strings.HasPrefix(line[i:], token) {
+					return append(regions, [2]int{i, len(line)}), false, 0
+				}
+			}
+			i++
+		}
+	}
+	if inBlock {
+		// The block comment opened right at the end of the line
+		return append(regions, [2]int{start, len(line)}), true, 0
+	}
+	return regions, false, quote
+}
+
+// blank returns line with the bytes in regions replaced by spaces, keeping
+// every other byte at its offset.
+func blank(line string, regions [][2]int) string {
+	if len(regions) == 0 {
+		return line
+	}
+	b := []byte(line)
+	for _, region := range regions {
+		for i := region[0]; i < region[1]; i++ {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// within reports whether the span loc lies inside one of regions.
+func within(loc []int, regions [][2]int) bool {
+	for _, region := range regions {
+		if loc[0] >= region[0] && loc[1] <= region[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// styleExtensions are styling languages whose class names, colors and
+// selectors routinely contain short tokens such as "DES" or "RC4".
+var styleExtensions = map[string]bool{
+	".css":    true,
+	".less":   true,
+	".scss":   true,
+	".styl":   true,
+	".stylus": true,
+}
+
+// shortTokenAlgorithms are the short, generic names that are only reported in
+// styling files when they appear inside a comment or a url(...) reference.
+// Keys are upper case.
+var shortTokenAlgorithms = map[string]bool{
+	"3DES": true,
+	"AES":  true,
+	"CBC":  true,
+	"CFB":  true,
+	"CTR":  true,
+	"DES":  true,
+	"DSA":  true,
+	"ECB":  true,
+	"ECC":  true,
+	"GCM":  true,
+	"GOST": true,
+	"MD5":  true,
+	"OFB":  true,
+	"RC2":  true,
+	"RC4":  true,
+	"RC5":  true,
+	"RSA":  true,
+	"SM2":  true,
+	"SM3":  true,
+	"SM4":  true,
+}
+
+// styleRegions returns the byte ranges of a styling file line that are
+// comments or url(...) references. inComment tells whether the line starts
+// inside a block comment, and the returned flag whether one is still open at
+// the end of the line.
+func styleRegions(line string, inComment bool) ([][2]int, bool) {
+	var regions [][2]int
+	lower := strings.ToLower(line)
+	for i := 0; i < len(line); {
+		start, end := i, len(line)
+		if !inComment {
+			start = len(line)
+			opener := ""
+			for _, token := range []string{"/*", "//", "url("} {
+				if j := strings.Index(lower[i:], token); j != -1 && i+j < start {
+					start, opener = i+j, token
+				}
+			}
+			switch opener {
+			case "":
+				return regions, false
+			case "//":
+				return append(regions, [2]int{start, len(line)}), false
+			case "url(":
+				if j := strings.Index(line[start:], ")"); j != -1 {
+					end = start + j + 1
+				}
+				regions = append(regions, [2]int{start, end})
+				i = end
+				continue
+			}
+			inComment = true
+			i = start + 2
+		}
+		j := strings.Index(line[i:], "*/")
+		if j == -1 {
+			return append(regions, [2]int{start, len(line)}), true
+		}
+		end = i + j + 2
+		regions = append(regions, [2]int{start, end})
+		inComment = false
+		i = end
+	}
+	return regions, inComment
+}
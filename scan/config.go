@@ -0,0 +1,225 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// algorithmPattern is an additional algorithm pattern loaded from a -config
+// file. Matches are reported under Name rather than the matched text.
+type algorithmPattern struct {
+	Name     string `json:"name"`
+	Regexp   string `json:"regexp"`
+	Severity string `json:"severity"` // Optional, defaults to "ok"
+	re       *regexp.Regexp
+}
+
+// config is the JSON document read by -config, for example:
+//
+//	{
+//		"patterns": [{"name": "AcmeCipher", "regexp": "\\bAcme(Cipher|Crypt)\\b"}],
+//		"extensions": {"disable": ["template", "docs"]}
+//	}
+type config struct {
+	Patterns   []algorithmPattern `json:"patterns"`
+	Extensions extensionSelection `json:"extensions"`
+}
+
+// extensionSelection picks the groups of validExtensions scanned, by their
+// names in extensionGroups. Extensions given with -ext or -lang are scanned
+// whatever their group, and -only-ext overrides the selection.
+type extensionSelection struct {
+	Groups  []string `json:"groups"`  // Scan only these groups; all of them if empty
+	Disable []string `json:"disable"` // Skip these groups
+}
+
+// selects reports whether the extensions of group are scanned.
+func (sel extensionSelection) selects(group string) bool {
+	return (len(sel.Groups) == 0 || slices.Contains(sel.Groups, group)) && !slices.Contains(sel.Disable, group)
+}
+
+// loadConfig reads and validates the configuration file at path, compiling
+// every pattern it defines. Fields the config does not define are rejected,
+// so that a misspelt key is not silently ignored. Once the file is parsed,
+// every problem in it is reported, joined with errors.Join, rather than only
+// the first.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	var errs []error
+	for _, group := range append(cfg.Extensions.Groups, cfg.Extensions.Disable...) {
+		if !slices.Contains(extensionGroups, group) {
+			errs = append(errs, fmt.Errorf("unknown extension group %q in %s; the groups are %s", group, path, strings.Join(extensionGroups, ", ")))
+		}
+	}
+	for i := range cfg.Patterns {
+		p := &cfg.Patterns[i]
+		if p.Name == "" || p.Regexp == "" {
+			errs = append(errs, fmt.Errorf("pattern %d in %s: both name and regexp are required", i+1, path))
+			continue
+		}
+		if p.re, err = regexp.Compile(p.Regexp); err != nil {
+			errs = append(errs, fmt.Errorf("pattern %q in %s: invalid regexp: %w", p.Name, path, err))
+		}
+		if p.Severity == "" {
+			p.Severity = "ok"
+		}
+		if _, ok := severityLevels[p.Severity]; !ok {
+			errs = append(errs, fmt.Errorf("pattern %q in %s: unknown severity %q; the severities are ok, deprecated and weak", p.Name, path, p.Severity))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &cfg, nil
+}
+
+// allowRule is one entry of a -allow file. A match is dropped when every
+// field that is set agrees with it.
+type allowRule struct {
+	algorithm string // Reported algorithm name, case-insensitive; empty for any
+	file      string // Glob matched against the relative path, see matchGlob
+	line      int    // 1-based line number, 0 for any
+	contains  string // Text the matched source line must contain
+}
+
+// loadAllowList reads the -allow file at name. Each non-blank line that does
+// not start with "#" is either
+//
+//	ALGORITHM [GLOB[:LINE]]   e.g. "MD5 src/legacy/*.go:12", "*" for any algorithm
+//	contains TEXT             e.g. "contains DESCRIPTION"
+func loadAllowList(name string) ([]allowRule, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var rules []allowRule
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if text, ok := strings.CutPrefix(line, "contains "); ok {
+			rules = append(rules, allowRule{contains: strings.TrimSpace(text)})
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d of %s: expected ALGORITHM [GLOB[:LINE]] or contains TEXT", i+1, name)
+		}
+		var rule allowRule
+		if fields[0] != "*" {
+			rule.algorithm = fields[0]
+		}
+		if len(fields) == 2 {
+			rule.file = fields[1]
+			if j := strings.LastIndex(rule.file, ":"); j != -1 {
+				if rule.line, err = strconv.Atoi(rule.file[j+1:]); err != nil || rule.line < 1 {
+					return nil, fmt.Errorf("line %d of %s: invalid line number %q", i+1, name, rule.file[j+1:])
+				}
+				rule.file = rule.file[:j]
+			}
+			if _, err := path.Match(rule.file, ""); err != nil {
+				return nil, fmt.Errorf("line %d of %s: invalid glob %q", i+1, name, rule.file)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// allowed reports whether m, found on the source line text, is suppressed
+// by a -allow rule.
+func (s *scanner) allowed(m Match, text string) bool {
+	for _, rule := range s.allow {
+		if (rule.algorithm == "" || strings.EqualFold(rule.algorithm, m.Algorithm)) &&
+			(rule.file == "" || matchGlob(rule.file, m.File)) &&
+			(rule.line == 0 || rule.line == m.Line) &&
+			strings.Contains(text, rule.contains) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredAlgorithm reports whether alg is dropped by -ignore-algo: an entry
+// matches the reported name itself and every more specific name it starts,
+// so "AES" drops "AES-256-GCM" too while "AES-256" keeps "AES-128". Case is
+// ignored.
+func (s *scanner) ignoredAlgorithm(alg string) bool {
+	alg = strings.ToUpper(alg)
+	for _, name := range s.ignoreAlgos {
+		if alg == name || strings.HasPrefix(alg, name+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultNonSecurityHints are the words that, on the same line as a hash,
+// suggest it computes a checksum, cache key or content fingerprint rather
+// than protecting anything. -non-security-hints replaces the list.
+const defaultNonSecurityHints = "checksum,crc,etag,cache,dedup,fingerprint"
+
+// nonSecurityUse reports whether line holds one of the -non-security-hints
+// words, or its plural, as a word of an identifier, ignoring case: "cache"
+// matches "cacheKey", "md5_cache" and "caches" but not "cachet" or "cached".
+// Hash matches on such a line are marked as likely non-security usages and
+// reported one severity level lower, so that an MD5 cache key shows as
+// deprecated and no longer trips -fail-on weak.
+func (s *scanner) nonSecurityUse(line string) bool {
+	for _, word := range identifierWords(line) {
+		for _, hint := range s.nonSecurityHints {
+			if word == hint || word == hint+"s" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// identifierWords splits line into the lower-case words its identifiers are
+// made of: runs of letters or of digits, split where camelCase starts a new
+// word, so "md5Checksum" is "md", "5" and "checksum".
+func identifierWords(line string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for _, r := range line {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(word) > 0 {
+			prev := word[len(word)-1]
+			if unicode.IsLower(prev) && unicode.IsUpper(r) || unicode.IsDigit(prev) != unicode.IsDigit(r) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,76 @@
+package scan
+
+import (
+	"crypto/sha256"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// queueDistinct hands the files held back by queue with -dedup-content to the
+// worker pool, one per content, so that a vendored copy or a second link to
+// the same file does not count its findings twice. Of the files with the same
+// content, the one whose displayed path sorts first is scanned, whatever
+// order the roots were walked in, so a tree always reports the same paths.
+// The others are counted as duplicates. A file that cannot be read is queued
+// for processFile to report.
+func (s *scanner) queueDistinct() {
+	jobs := s.held
+	s.held = nil
+	paths := make([]string, len(jobs))
+	for i, job := range jobs {
+		paths[i] = Match{Root: job.root, File: job.name}.Path()
+	}
+	order := make([]int, len(jobs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return paths[order[i]] < paths[order[j]] })
+
+	// Hashing reads every file, so it is shared out like scanning
+	sums := make([][sha256.Size]byte, len(jobs))
+	hashed := make([]bool, len(jobs))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < max(s.workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < len(jobs); i = int(next.Add(1) - 1) {
+				sums[i], hashed[i] = s.contentSum(jobs[i].path)
+			}
+		}()
+	}
+	wg.Wait()
+
+	first := make(map[[sha256.Size]byte]string)
+	for _, i := range order {
+		if hashed[i] {
+			if path, ok := first[sums[i]]; ok {
+				s.verbosef("Skipping %s: same content as %s\n", paths[i], path)
+				s.duplicates++
+				s.scanned.Add(1)
+				continue
+			}
+			first[sums[i]] = paths[i]
+		}
+		s.jobs <- jobs[i]
+	}
+}
+
+// contentSum hashes the content of the file at path, as openFile reads it,
+// reporting false if it cannot be read.
+func (s *scanner) contentSum(path string) (sum [sha256.Size]byte, ok bool) {
+	file, err := s.openFile(path)
+	if err != nil {
+		return sum, false
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return sum, false
+	}
+	h.Sum(sum[:0])
+	return sum, true
+}
@@ -0,0 +1,42 @@
+package scan
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestDedupContentIsDeterministic(t *testing.T) {
+	files := map[string]string{"unique.go": "// SHA1\n", "z/aes.go": "// AES\n"}
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("vendor/copy%02d/aes.go", i)] = "// AES\n"
+		files[fmt.Sprintf("third_party/m%02d/md5.go", i)] = "// MD5\n"
+	}
+	// t.TempDir numbers its directories, so dir sorts before other
+	dir := writeTree(t, files)
+	other := writeTree(t, map[string]string{"a/md5.go": "// MD5\n", "b/md5.go": "// MD5\n"})
+	want := []string{
+		filepath.Join(dir, "third_party/m00/md5.go") + " MD5",
+		filepath.Join(dir, "unique.go") + " SHA-1",
+		filepath.Join(dir, "vendor/copy00/aes.go") + " AES",
+	}
+
+	for run := 0; run < 10; run++ {
+		s := newTestScanner()
+		s.dedupContent = true
+		s.workers = 8
+		s.parallelRoots = 2
+		var got []string
+		for _, m := range scan(t, s, other, dir) {
+			got = append(got, m.Path()+" "+m.Algorithm)
+		}
+		sort.Strings(got)
+		if !sameList(got, want) {
+			t.Fatalf("run %d found %q, want %q", run, got, want)
+		}
+		if s.duplicates != 41 {
+			t.Errorf("run %d: %d duplicates, want 41", run, s.duplicates)
+		}
+	}
+}
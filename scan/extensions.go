@@ -0,0 +1,372 @@
+package scan
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// validExtensions are the extensions scanned by default, each mapped to its
+// group in extensionGroups, by which a -config file can select them.
+var validExtensions = map[string]string{
+	".abc":         "data",      // ABC notation file
+	".ada":         "compiled",  // Ada source code file
+	".agda":        "compiled",  // Agda source code file
+	".al":          "compiled",  // AL source code file
+	".applescript": "scripting", // AppleScript file
+	".asa":         "template",  // ASP source code file
+	".asax":        "template",  // ASP.NET application file
+	".ascx":        "template",  // ASP.NET user control file
+	".ashx":        "template",  // ASP.NET handler file
+	".asm":         "compiled",  // Assembly language source code file
+	".asmx":        "template",  // ASP.NET web service file
+	".asp":         "template",  // ASP classic source code file
+	".au3":         "scripting", // AutoIt script file
+	".awk":         "shell",     // Awk script file
+	".bas":         "compiled",  // BASIC source code file
+	".bat":         "shell",     // Batch script file
+	".bdy":         "compiled",  // BETA source code file
+	".bpl":         "other",     // Delphi package library file
+	".c":           "compiled",  // C source code file
+	".cbl":         "compiled",  // COBOL source code file
+	".cfm":         "template",  // ColdFusion Markup Language file
+	".cl":          "compiled",  // OpenCL source code file
+	".clixml":      "compiled",  // C++/CLI source code file
+	".clj":         "scripting", // Clojure source code file
+	".cls":         "compiled",  // Visual Basic class file
+	".cmd":         "shell",     // Windows Command script file
+	".coffee":      "scripting", // CoffeeScript file
+	".cpp":         "compiled",  // C++ source code file
+	".cr":          "compiled",  // Crystal source code file
+	".cs":          "compiled",  // C# source code file
+	".cshtml":      "template",  // C# Razor file
+	".cson":        "config",    // CSON (Coffeescript Object Notation) file
+	".css":         "style",     // Cascading Style Sheets file
+	".cu":          "compiled",  // CUDA source code file
+	".cxx":         "compiled",  // C++ source code file
+	".d":           "compiled",  // D source code file
+	".dart":        "compiled",  // Dart source code file
+	".dbm":         "other",     // GNU DBM database file
+	".dbml":        "data",      // Database Markup Language file
+	".dbpro":       "compiled",  // DarkBASIC Pro source code file
+	".dbpro3":      "compiled",  // DarkBASIC Pro 3 source code file
+	".def":         "config",    // Module-definition file
+	".dg":          "scripting", // DG Script file
+	".dml":         "data",      // Data Manipulation Language file
+	".do":          "scripting", // Stata script file
+	".dsp":         "other",     // Digital Signal Processor file
+	".e":           "compiled",  // Eiffel source code file
+	".ecl":         "compiled",  // ECL source code file
+	".edn":         "config",    // Extensible Data Notation file
+	".ejs":         "template",  // Embedded JavaScript file
+	".el":          "scripting", // Emacs Lisp source code file
+	".elixir":      "scripting", // Elixir source code file
+	".elm":         "compiled",  // Elm source code file
+	".epl":         "scripting", // Euphoria source code file
+	".erl":         "scripting", // Erlang source code file
+	".es":          "scripting", // ECMAScript file
+	".ex":          "scripting", // Elixir source code file
+	".exs":         "scripting", // Elixir script file
+	".f":           "compiled",  // Fortran source code file
+	".f03":         "compiled",  // Fortran 2003 source code file
+	".f08":         "compiled",  // Fortran 2008 source code file
+	".f77":         "compiled",  // Fortran 77 source code file
+	".f90":         "compiled",  // Fortran 90 source code file
+	".f95":         "compiled",  // Fortran 95 source code file
+	".feature":     "docs",      // Gherkin feature file
+	".fish":        "shell",     // Fish shell script file
+	".forth":       "scripting", // Forth source code file
+	".fpp":         "compiled",  // Fortran preprocessed source code file
+	".frt":         "scripting", // Forth source code file
+	".fs":          "compiled",  // F# source code file
+	".fsi":         "compiled",  // F# interface file
+	".fsx":         "scripting", // F# script file
+	".fth":         "scripting", // Forth source code file
+	".ftn":         "compiled",  // Fortran source code file
+	".fy":          "scripting", // Forth source code file
+	".fzp":         "markup",    // Fritzing project file
+	".gameproj":    "markup",    // GameMaker Studio project file
+	".gd":          "scripting", // GDScript source code file
+	".ged":         "data",      // GEDCOM file
+	".gemspec":     "scripting", // RubyGem specification file
+	".glsl":        "compiled",  // OpenGL Shading Language file
+	".gml":         "scripting", // GameMaker Language file
+	".gms":         "scripting", // GameMaker Studio script file
+	".go":          "compiled",  // Go source code file
+	".gpt":         "scripting", // GPLT script file
+	".groovy":      "compiled",  // Groovy source code file
+	".gs":          "scripting", // Google Apps Script file
+	".gy":          "compiled",  // Groovy source code file
+	".h":           "compiled",  // C header file
+	".h++":         "compiled",  // C++ header file
+	".haml":        "template",  // Haml source code file
+	".hbs":         "template",  // Handlebars source code file
+	".hcl":         "config",    // HashiCorp Configuration Language file
+	".hh":          "compiled",  // C++ header file
+	".hlsl":        "compiled",  // High-Level Shading Language file
+	".hoon":        "compiled",  // Hoon source code file
+	".hpp":         "compiled",  // C++ header file
+	".hs":          "compiled",  // Haskell source code file
+	".htaccess":    "config",    // Apache .htaccess file
+	".htc":         "template",  // HTC source code file
+	".hx":          "compiled",  // Haxe source code file
+	".hxml":        "config",    // Haxe build file
+	".hxx":         "compiled",  // C++ header file
+	".i":           "data",      // IDL source code file
+	".iced":        "scripting", // IcedCoffeeScript file
+	".icl":         "compiled",  // Clean source code file
+	".idc":         "data",      // IDL source code file
+	".ini":         "config",    // INI configuration file
+	".io":          "scripting", // Io source code file
+	".j":           "scripting", // J source code file
+	".java":        "compiled",  // Java source code file
+	".jison":       "compiled",  // Jison grammar file
+	".jl":          "scripting", // Julia source code file
+	".js":          "scripting", // JavaScript source code file
+	".json":        "config",    // JSON data file
+	".jsp":         "template",  // JavaServer Pages file
+	".jsx":         "scripting", // JSX (JavaScript XML) file
+	".julia":       "scripting", // Julia source code file
+	".kix":         "scripting", // Kixtart script file
+	".kt":          "compiled",  // Kotlin source code file
+	".l":           "compiled",  // Lex source code file
+	".less":        "style",     // Less source code file
+	".lfe":         "scripting", // Lisp Flavoured Erlang source code file
+	".lgt":         "scripting", // Logtalk source code file
+	".lidr":        "compiled",  // Literate Haskell source code file
+	".liquid":      "template",  // Liquid template file
+	".lisp":        "scripting", // Lisp source code file
+	".logtalk":     "scripting", // Logtalk source code file
+	".ls":          "scripting", // LiveScript source code file
+	".lsp":         "scripting", // Lisp source code file
+	".lua":         "scripting", // Lua source code file
+	".m":           "compiled",  // Objective-C source code file
+	".m4":          "scripting", // M4 source code file
+	".mak":         "config",    // Makefile
+	".maki":        "markup",    // Mapnik XML file
+	".markdown":    "docs",      // Markdown file
+	".mathematica": "scripting", // Mathematica source code file
+	".matlab":      "scripting", // MATLAB source code file
+	".max":         "scripting", // MaxScript source code file
+	".md":          "docs",      // Markdown file
+	".mel":         "scripting", // Maya Embedded Language script file
+	".mi":          "compiled",  // Objective-C source code file
+	".mib":         "data",      // SNMP MIB file
+	".mk":          "config",    // Makefile
+	".ml":          "compiled",  // OCaml source code file
+	".mm":          "compiled",  // Objective-C++ source code file
+	".mo":          "compiled",  // Modelica source code file
+	".mod":         "compiled",  // Modula-2 source code file
+	".moo":         "scripting", // MOO source code file
+	".moon":        "scripting", // MoonScript source code file
+	".mq4":         "scripting", // MQL4 source code file
+	".mq5":         "scripting", // MQL5 source code file
+	".mqh":         "scripting", // MQL Header file
+	".mtml":        "template",  // MTML markup language file
+	".muf":         "scripting", // Multi-User Forth source code file
+	".mustache":    "template",  // Mustache template file
+	".n":           "compiled",  // Nemerle source code file
+	".ncl":         "scripting", // Netsuite script file
+	".nim":         "compiled",  // Nim source code file
+	".nix":         "config",    // Nix script file
+	".nl":          "scripting", // Netsuite script file
+	".nse":         "scripting", // Nullsoft Scriptable Install System script file
+	".nu":          "scripting", // Nu source code file
+	".nut":         "scripting", // Squirrel source code file
+	".o":           "other",     // Object file
+	".odin":        "compiled",  // Odin source code file
+	".one":         "other",     // OneNote file
+	".ops":         "other",     // Operators source code file
+	".org":         "docs",      // Org mode file
+	".ox":          "scripting", // Ox source code file
+	".oxygene":     "compiled",  // Oxygene source code file
+	".p":           "compiled",  // Pascal source code file
+	".p6":          "scripting", // Perl 6 source code file
+	".pas":         "compiled",  // Pascal source code file
+	".pascal":      "compiled",  // Pascal source code file
+	".pd":          "data",      // Pure Data patch file
+	".php":         "scripting", // PHP source code file
+	".php3":        "scripting", // PHP 3 source code file
+	".php4":        "scripting", // PHP 4 source code file
+	".php5":        "scripting", // PHP 5 source code file
+	".phps":        "scripting", // PHP script source file
+	".phpt":        "scripting", // PHP test script file
+	".phtml":       "template",  // PHP Hypertext Preprocessor file
+	".pig":         "scripting", // Pig script file
+	".pike":        "scripting", // Pike source code file
+	".pl":          "scripting", // Perl source code file
+	".plist":       "markup",    // Property list file
+	".plsql":       "scripting", // PL/SQL script file
+	".pm":          "scripting", // Perl module file
+	".pod":         "docs",      // Perl POD documentation file
+	".pot":         "data",      // Portable Object Template file
+	".prc":         "other",     // Palm Resource file
+	".pro":         "scripting", // Prolog source code file
+	".proto":       "data",      // Protocol Buffers file
+	".ps1":         "shell",     // PowerShell script file
+	".ps1xml":      "markup",    // PowerShell XML format file
+	".psm1":        "shell",     // PowerShell module file
+	".pug":         "template",  // Pug source code file
+	".purs":        "compiled",  // PureScript source code file
+	".ipynb":       "scripting", // Jupyter notebook, code cells only
+	".py":          "scripting", // Python source code file
+	".pyc":         "other",     // Python compiled file
+	".pyd":         "other",     // Python dynamic library file
+	".pyi":         "scripting", // Python stub file
+	".pyo":         "other",     // Python optimized file
+	".pyt":         "scripting", // Python test file
+	".pyx":         "scripting", // Cython source code file
+	".qml":         "scripting", // QML source code file
+	".r":           "scripting", // R source code file
+	".r3":          "scripting", // R3 source code file
+	".rake":        "scripting", // Ruby Rakefile
+	".rb":          "scripting", // Ruby source code file
+	".rbbas":       "compiled",  // REALbasic source code file
+	".rbi":         "scripting", // Ruby interface file
+	".rbx":         "scripting", // Ruby source code file
+	".rc":          "other",     // Resource file
+	".rcp":         "markup",    // Eclipse Rich Client Platform file
+	".re":          "compiled",  // Reason source code file
+	".reb":         "scripting", // Rebol script file
+	".resx":        "markup",    // .NET Resource file
+	".rhtml":       "template",  // Ruby HTML file
+	".rkt":         "scripting", // Racket source code file
+	".rktl":        "scripting", // Racket library file
+	".robo":        "scripting", // RoboFont extension file
+	".rpy":         "scripting", // Ren'Py script file
+	".rql":         "data",      // ReQL query language file
+	".rs":          "compiled",  // Rust source code file
+	".rst":         "docs",      // reStructuredText file
+	".ruby":        "scripting", // Ruby source code file
+	".s":           "compiled",  // Assembly language source code file
+	".sage":        "scripting", // Sage source code file
+	".scala":       "compiled",  // Scala source code file
+	".scm":         "scripting", // Scheme source code file
+	".scss":        "style",     // Sass source code file
+	".sh":          "shell",     // Shell script file
+	".sls":         "config",    // SaltStack state file
+	".sml":         "compiled",  // Standard ML source code file
+	".sql":         "data",      // SQL script file
+	".srt":         "docs",      // SubRip subtitle file
+	".ss":          "scripting", // Scheme source code file
+	".st":          "scripting", // Smalltalk source code file
+	".stl":         "other",     // Stereolithography file
+	".styl":        "style",     // Stylus stylesheet file
+	".stylus":      "style",     // Stylus stylesheet file
+	".swift":       "compiled",  // Swift source code file
+	".swm":         "other",     // StarWriter Master document file
+	".t":           "scripting", // Tcl/Tk script file
+	".tcl":         "scripting", // Tcl script file
+	".tex":         "docs",      // LaTeX source code file
+	".textile":     "docs",      // Textile source code file
+	".toml":        "config",    // TOML configuration file
+	".ts":          "scripting", // TypeScript source code file
+	".tsx":         "scripting", // TypeScript React source code file
+	".twig":        "template",  // Twig template file
+	".txl":         "scripting", // TXL source code file
+	".v":           "compiled",  // Verilog source code file
+	".vala":        "compiled",  // Vala source code file
+	".vapi":        "compiled",  // Vala API file
+	".vb":          "compiled",  // Visual Basic source code file
+	".vba":         "scripting", // VBA source code file
+	".vbs":         "scripting", // VBScript file
+	".vcl":         "config",    // Varnish Configuration Language file
+	".vh":          "compiled",  // VHDL source code file
+	".vhd":         "compiled",  // VHDL source code file
+	".vhdl":        "compiled",  // VHDL source code file
+	".vim":         "scripting", // Vim script file
+	".x":           "scripting", // XQuery source code file
+	".xaml":        "markup",    // XAML file
+	".xht":         "markup",    // XHTML file
+	".xhtml":       "markup",    // XHTML file
+	".xlsm":        "other",     // Excel Open XML Macro-Enabled Spreadsheet file
+	".xpl":         "scripting", // XProc source code file
+	".xsd":         "markup",    // XML Schema Definition file
+	".xsl":         "markup",    // XSLT stylesheet file
+	".y":           "compiled",  // Yacc source code file
+	".yaml":        "config",    // YAML file
+	".yang":        "data",      // YANG data modeling language file
+	".yap":         "scripting", // Yapp source code file
+	".yml":         "config",    // YAML file
+	".yxx":         "compiled",  // Yacc++ source code file
+	".zsh":         "shell",     // Z shell script file
+}
+
+// extensionGroups are the groups of validExtensions: source in compiled and
+// interpreted languages, shell scripts, server-side and client templates,
+// stylesheets, XML and other markup, configuration, schemas, queries and
+// data, documentation, and binary or unusual formats.
+var extensionGroups = []string{"compiled", "scripting", "shell", "template", "style", "markup", "config", "data", "docs", "other"}
+
+// hasValidExtension reports whether name has an extension selected for
+// scanning.
+func (s *scanner) hasValidExtension(name string) bool {
+	if s.allExtensions {
+		return true
+	}
+	return s.extensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// parseExtensions splits a comma-separated extension list such as
+// ".foo,bar" into normalized, dot-prefixed, lower-case extensions.
+func parseExtensions(list string) []string {
+	var exts []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// docExtensions are documentation formats. Matches in them mention an
+// algorithm rather than use it, so they are reported with source "doc", and
+// -skip-docs leaves these files out entirely.
+var docExtensions = map[string]bool{
+	".adoc":     true,
+	".asciidoc": true,
+	".markdown": true,
+	".md":       true,
+	".org":      true,
+	".pod":      true,
+	".rdoc":     true,
+	".rst":      true,
+	".tex":      true,
+	".texi":     true,
+	".txt":      true,
+}
+
+// isDoc reports whether the file name has a documentation extension.
+func isDoc(name string) bool {
+	return docExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// keyFileExtensions are certificate, private key and keystore formats. With
+// -detect-key-files a file with one of them is reported as committed key
+// material by its extension alone, since keystores are binary and the text
+// scan would never see what they hold.
+var keyFileExtensions = map[string]bool{
+	".cer":      true,
+	".crt":      true,
+	".der":      true,
+	".jks":      true,
+	".key":      true,
+	".keystore": true,
+	".p12":      true,
+	".p8":       true,
+	".pem":      true,
+	".pfx":      true,
+	".ppk":      true,
+}
+
+// keyFileAlgorithm is the name -detect-key-files findings are reported under.
+const keyFileAlgorithm = "KEY FILE"
+
+// isKeyFile reports whether the file name is reported by -detect-key-files.
+func (s *scanner) isKeyFile(name string) bool {
+	return s.detectKeyFiles && keyFileExtensions[strings.ToLower(filepath.Ext(s.innerName(name)))]
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package scan
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFIFOIsSkipped(t *testing.T) {
+	dir := writeTree(t, map[string]string{"main.go": "// AES\n"})
+	if err := syscall.Mkfifo(filepath.Join(dir, "pipe.go"), 0o644); err != nil {
+		t.Skip("cannot create a FIFO:", err)
+	}
+	done := make(chan []string)
+	go func() { done <- found(scan(t, newTestScanner(), dir)) }()
+	select {
+	case got := <-done:
+		if want := []string{"main.go:1 AES"}; !sameList(got, want) {
+			t.Errorf("found %q, want %q", got, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("scan blocked on a FIFO")
+	}
+}
@@ -0,0 +1,59 @@
+package scan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gzipped returns content compressed with gzip.
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	dir := t.TempDir()
+	content := "-- schema\n" + strings.Repeat("SELECT 1;\n", 100) + "SELECT MD5(password);\n"
+	if err := os.WriteFile(filepath.Join(dir, "dump.sql.gz"), gzipped(t, content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := found(scan(t, newTestScanner(), dir)); len(got) != 0 {
+		t.Errorf("found %q without -decompress, want nothing", got)
+	}
+	s := newTestScanner()
+	s.decompress = true
+	got := found(scan(t, s, dir))
+	if want := []string{"dump.sql.gz:102 MD5"}; !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+func TestDecompressTruncated(t *testing.T) {
+	dir := t.TempDir()
+	data := gzipped(t, strings.Repeat("SELECT SHA1(x);\n", 1000))
+	if err := os.WriteFile(filepath.Join(dir, "dump.sql.gz"), data[:len(data)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestScanner()
+	s.decompress = true
+	scan(t, s, dir)
+	if len(s.scanErrors) != 1 || !strings.Contains(s.scanErrors[0].Error, "unexpected EOF") {
+		t.Errorf("scanErrors = %v, want the truncated stream reported", s.scanErrors)
+	}
+	if !s.failed {
+		t.Error("a truncated file left the scan marked complete")
+	}
+}
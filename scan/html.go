@@ -0,0 +1,96 @@
+package scan
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"sort"
+)
+
+// reportHTML is the page written by -format html. It is self-contained, with
+// its styles inline, so the report can be mailed or attached as one file.
+//
+//go:embed report.html
+var reportHTML string
+
+var htmlTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+type htmlReport struct {
+	Targets      []string
+	FilesScanned int
+	Total        int
+	Roots        []*rootStats   // With several roots, the files and findings of each
+	Severities   []htmlSeverity // Most severe first
+	Errors       []scanError    // Files and directories that could not be scanned in full
+}
+
+// htmlSeverity holds the matches of one severity, grouped by file.
+type htmlSeverity struct {
+	Name  string
+	Count int
+	Files []htmlFile
+}
+
+type htmlFile struct {
+	Path    string
+	Matches []htmlMatch
+}
+
+type htmlMatch struct {
+	Algorithm   string
+	Category    string
+	Usage       string
+	Source      string
+	Author      string
+	Commit      string
+	Date        string
+	Line, Col   int
+	Context     string
+	Remediation string // With -hints
+}
+
+// writeHTML writes the results to w as a standalone HTML page grouping the
+// matches by severity and then by file, each in a collapsible section.
+func (s *scanner) writeHTML(w io.Writer, targets []string) error {
+	report := htmlReport{
+		Targets:      targets,
+		FilesScanned: s.filesScanned,
+		Total:        len(s.matches),
+		Roots:        s.rootSummary(),
+		Errors:       s.scanErrors,
+	}
+
+	bySeverity := make(map[string][]Match)
+	for _, m := range s.matches {
+		bySeverity[m.Severity] = append(bySeverity[m.Severity], m)
+	}
+	for severity, matches := range bySeverity {
+		entry := htmlSeverity{Name: severity, Count: len(matches)}
+		files, byPath := groupByFile(matches)
+		for _, file := range files {
+			hf := htmlFile{Path: file}
+			for _, m := range byPath[file] {
+				hf.Matches = append(hf.Matches, htmlMatch{
+					Algorithm:   m.Algorithm,
+					Category:    m.Category,
+					Usage:       m.Usage,
+					Source:      m.Source,
+					Author:      m.Author,
+					Commit:      m.Commit,
+					Date:        m.Date,
+					Line:        m.Line,
+					Col:         m.Col,
+					Context:     excerpt(m.Context, m.contextCol, s.contextWidth),
+					Remediation: s.remediation(m.Algorithm),
+				})
+			}
+			entry.Files = append(entry.Files, hf)
+		}
+		report.Severities = append(report.Severities, entry)
+	}
+	sort.Slice(report.Severities, func(i, j int) bool {
+		return severityLevels[report.Severities[i].Name] > severityLevels[report.Severities[j].Name]
+	})
+
+	return htmlTemplate.Execute(w, report)
+}
@@ -0,0 +1,305 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+)
+
+// dumpvarsIgnore is the name of the per-directory ignore file for rules that
+// only concern scanning. It uses .gitignore syntax and is applied after the
+// -ignore-files in the same directory, even with -no-gitignore.
+const dumpvarsIgnore = ".dumpvarsignore"
+
+// defaultIgnoreFiles are the ignore files read unless -ignore-files names
+// others: those of Git and Mercurial, whichever a tree has. Packaging ignore
+// files such as .npmignore often exclude the sources themselves, so they are
+// only read when asked for.
+const defaultIgnoreFiles = ".gitignore,.hgignore"
+
+// loadIgnoreRules compiles the ignore files in dir that apply to the scan.
+func (s *scanner) loadIgnoreRules(dir string) ignoreFile {
+	var patterns ignoreFile
+	if !s.noGitIgnore {
+		patterns = s.loadIgnoreFiles(dir, s.ignoreFiles)
+	}
+	return append(patterns, s.loadIgnoreFile(filepath.Join(dir, dumpvarsIgnore))...)
+}
+
+// loadIgnoreFiles compiles the ignore files called names in dir into a
+// single list, in the order given, so that a "!" pattern in a later file can
+// re-include what an earlier one excluded. .hgignore and .dockerignore are
+// translated from their own syntax; any other file is read as a .gitignore,
+// whose syntax .npmignore shares.
+func (s *scanner) loadIgnoreFiles(dir string, names []string) ignoreFile {
+	var patterns ignoreFile
+	for _, name := range names {
+		ignorePath := filepath.Join(dir, name)
+		switch name {
+		case ".hgignore":
+			patterns = append(patterns, s.loadHgIgnore(ignorePath)...)
+		case ".dockerignore":
+			patterns = append(patterns, s.loadDockerIgnore(ignorePath)...)
+		default:
+			patterns = append(patterns, s.loadIgnoreFile(ignorePath)...)
+		}
+	}
+	return patterns
+}
+
+// loadIgnoreFile compiles the gitignore-style file at ignorePath.
+func (s *scanner) loadIgnoreFile(ignorePath string) ignoreFile {
+	return s.readIgnoreFile(ignorePath, compileIgnoreLine)
+}
+
+// loadDockerIgnore compiles the .dockerignore at ignorePath. Its patterns
+// are .gitignore patterns anchored at the directory of the file, so "*.md"
+// only matches there rather than at any depth.
+func (s *scanner) loadDockerIgnore(ignorePath string) ignoreFile {
+	return s.readIgnoreFile(ignorePath, func(line string) (pathMatcher, error) {
+		return compileIgnoreLine("/" + strings.TrimPrefix(strings.TrimSpace(line), "/"))
+	})
+}
+
+// loadHgIgnore compiles the .hgignore at ignorePath. Patterns are regular
+// expressions searched for anywhere in the path unless a "syntax: glob" line
+// switches to globs, which match at any depth like .gitignore patterns, or a
+// pattern has its own "re:", "glob:" or "rootglob:" prefix. "#" starts a
+// comment anywhere on a line unless escaped as "\#".
+func (s *scanner) loadHgIgnore(ignorePath string) ignoreFile {
+	syntax := "regexp"
+	return s.readIgnoreFile(ignorePath, func(line string) (pathMatcher, error) {
+		for i := 0; i < len(line); i++ {
+			if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+				line = line[:i]
+				break
+			}
+		}
+		line = strings.TrimSpace(strings.ReplaceAll(line, `\#`, "#"))
+		if line == "" {
+			return nil, nil
+		}
+		if rest, ok := strings.CutPrefix(line, "syntax:"); ok {
+			syntax = strings.TrimSpace(rest)
+			return nil, nil
+		}
+		kind, pattern := syntax, line
+		if prefix, rest, ok := strings.Cut(line, ":"); ok {
+			switch prefix {
+			case "re", "regexp", "relre", "glob", "relglob", "rootglob":
+				kind, pattern = prefix, rest
+			}
+		}
+		switch kind {
+		case "re", "regexp", "relre":
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			return regexpMatcher{re}, nil
+		case "glob", "relglob":
+			if !strings.HasPrefix(pattern, "**/") {
+				pattern = "**/" + pattern
+			}
+			return compileIgnoreLine(pattern)
+		case "rootglob":
+			return compileIgnoreLine("/" + pattern)
+		}
+		return nil, fmt.Errorf("unknown syntax %q", kind)
+	})
+}
+
+// readIgnoreFile compiles the ignore file at ignorePath, handing each line
+// other than blanks and "#" comments, without a leading "!", to compile,
+// which returns a nil matcher for lines that hold no pattern. A missing or
+// unreadable file yields no patterns, and a malformed line is skipped with a
+// warning, see warnf, so that one bad ignore file cannot abort the whole
+// scan.
+func (s *scanner) readIgnoreFile(ignorePath string, compile func(line string) (pathMatcher, error)) ignoreFile {
+	data, err := os.ReadFile(ignorePath)
+	if os.IsNotExist(err) {
+		// If the file doesn't exist, return empty patterns
+		return nil
+	}
+	if err != nil {
+		s.warnf(ignorePath, "ignoring %s: %w", ignorePath, err)
+		return nil
+	}
+
+	var patterns ignoreFile
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Negation is tracked here rather than by go-gitignore so that a
+		// re-include can be weighed against patterns from other files
+		negate := strings.HasPrefix(line, "!")
+		matcher, err := compile(strings.TrimPrefix(line, "!"))
+		if err != nil {
+			s.warnf(ignorePath, "%s:%d: ignoring line: %w", ignorePath, i+1, err)
+			continue
+		}
+		if matcher != nil {
+			patterns = append(patterns, ignorePattern{matcher: matcher, negate: negate})
+		}
+	}
+	return patterns
+}
+
+// compileIgnoreLine compiles a .gitignore line with go-gitignore. The library
+// silently drops a line whose pattern does not translate to a valid regular
+// expression, such as "foo\" or "[", so the translation is compiled here
+// first to report the error.
+func compileIgnoreLine(line string) (pathMatcher, error) {
+	if _, err := regexp.Compile(gitignoreRegexp(line)); err != nil {
+		return nil, fmt.Errorf("malformed pattern %q: %w", line, err)
+	}
+	return gitignore.CompileIgnoreLines(line), nil
+}
+
+// gitignoreSlashGlob matches the lines go-gitignore anchors at the
+// directory of the ignore file, such as "docs/*.html".
+var gitignoreSlashGlob = regexp.MustCompile(`([^\/+])/.*\*\.`)
+
+// gitignoreRegexp returns the regular expression go-gitignore translates a
+// .gitignore line to, or "" for a line without a pattern. It follows
+// getPatternFromLine in the version of go-gitignore in go.mod, and must be
+// kept in step with it.
+func gitignoreRegexp(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+	line = strings.Trim(line, " ")
+	if line == "" {
+		return ""
+	}
+	line = strings.TrimPrefix(line, "!")
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+		line = line[1:]
+	}
+	if gitignoreSlashGlob.MatchString(line) && line[0] != '/' {
+		line = "/" + line
+	}
+	line = strings.ReplaceAll(line, ".", `\.`)
+	const magicStar = "#$~"
+	if strings.HasPrefix(line, "/**/") {
+		line = line[1:]
+	}
+	line = strings.ReplaceAll(line, "/**/", "(/|/.+/)")
+	line = strings.ReplaceAll(line, "**/", "(|."+magicStar+"/)")
+	line = strings.ReplaceAll(line, "/**", "(|/."+magicStar+")")
+	line = strings.ReplaceAll(line, `\*`, `\`+magicStar)
+	line = strings.ReplaceAll(line, "*", "([^/]*)")
+	line = strings.ReplaceAll(line, "?", `\?`)
+	line = strings.ReplaceAll(line, magicStar, "*")
+	expr := line + "(|/.*)$"
+	if strings.HasSuffix(line, "/") {
+		expr = line + "(|.*)$"
+	}
+	if strings.HasPrefix(expr, "/") {
+		return "^(|/)" + expr[1:]
+	}
+	return "^(|.*/)" + expr
+}
+
+// pathMatcher matches slash-separated paths relative to the directory of the
+// ignore file a pattern comes from.
+type pathMatcher interface {
+	MatchesPath(path string) bool
+}
+
+// regexpMatcher is a .hgignore regular expression, matching paths it finds
+// anywhere in.
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexpMatcher) MatchesPath(path string) bool {
+	return m.re.MatchString(path)
+}
+
+// ignorePattern is a single ignore file line, compiled without its leading
+// "!" when negate is set.
+type ignorePattern struct {
+	matcher pathMatcher
+	negate  bool
+}
+
+// ignoreFile holds the patterns of one .gitignore in file order.
+type ignoreFile []ignorePattern
+
+// ignoreRules holds the compiled .gitignore of every directory entered by a
+// walk, keyed by the directory's slash-separated path relative to the scan
+// root ("." for the root itself).
+type ignoreRules map[string]ignoreFile
+
+// matches reports whether relPath is ignored. As in Git, the last matching
+// pattern decides, a "!" pattern re-includes what earlier ones excluded, and
+// patterns in deeper .gitignore files take precedence over shallower ones.
+// Each file's patterns are matched against the path relative to the
+// directory containing it, so they only affect that subtree.
+func (rules ignoreRules) matches(relPath string, isDir bool) bool {
+	var dirs []string
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == "." {
+			break
+		}
+	}
+
+	ignored := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		subPath := relPath
+		if dirs[i] != "." {
+			subPath = strings.TrimPrefix(relPath, dirs[i]+"/")
+		}
+		for _, p := range rules[dirs[i]] {
+			if p.matcher.MatchesPath(subPath) || (isDir && p.matcher.MatchesPath(subPath+"/")) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// shouldIgnore reports whether the file or directory at path, whose
+// slash-separated path relative to the scan root is relPath, should be
+// skipped, logging the reason with -verbose.
+func (s *scanner) shouldIgnore(path string, relPath string, ignorePatterns ignoreRules, isDir bool) bool {
+	reason := s.skipReason(path, relPath, ignorePatterns, isDir)
+	if reason != "" {
+		s.verbosef("Skipping %s: %s\n", path, reason)
+	}
+	return reason != ""
+}
+
+// skipReason returns why shouldIgnore skips path, or "" if it is scanned.
+func (s *scanner) skipReason(path string, relPath string, ignorePatterns ignoreRules, isDir bool) string {
+	if relPath == "." {
+		return ""
+	}
+
+	if strings.HasSuffix(relPath, ".git") {
+		return "git metadata"
+	}
+
+	if isDir {
+		if s.excluded(relPath) {
+			return "-exclude"
+		}
+	} else if reason := s.fileSkipReason(path, relPath); reason != "" {
+		return reason
+	}
+
+	if ignorePatterns.matches(relPath, isDir) && !s.forceIncluded(relPath, isDir) && !(isDir && s.leadsToForced(relPath)) {
+		return "ignore file"
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package scan
+
+import (
+	"testing"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+)
+
+func TestGitignoreRegexpFollowsLibrary(t *testing.T) {
+	// Each line with a path it matches, so that go-gitignore reports the
+	// regular expression it compiled the line to. readIgnoreFile strips the
+	// "!" of negated lines before they are compiled, so none are listed
+	tests := []struct{ line, path string }{
+		{"*.log", "logs/app.log"},
+		{"/build", "build/out.go"},
+		{"docs/*.html", "docs/index.html"},
+		{"**/vendor", "a/b/vendor/x.go"},
+		{"/**/gen/", "gen/x.go"},
+		{"a/**/b", "a/x/y/b"},
+		{"lib/**", "lib/x/y.go"},
+		{"file?.txt", "file?.txt"},
+		{`\!important.txt`, "!important.txt"},
+		{`\#hash`, "#hash"},
+		{"  spaced.go  ", "spaced.go"},
+	}
+	for _, tt := range tests {
+		matched, how := gitignore.CompileIgnoreLines(tt.line).MatchesPathHow(tt.path)
+		if !matched && how == nil {
+			t.Errorf("%q does not match %q; pick another path", tt.line, tt.path)
+			continue
+		}
+		if got, want := gitignoreRegexp(tt.line), how.Pattern.String(); got != want {
+			t.Errorf("gitignoreRegexp(%q) = %q, go-gitignore compiles %q", tt.line, got, want)
+		}
+	}
+}
+
+func TestCompileIgnoreLineMalformed(t *testing.T) {
+	for _, line := range []string{`foo\`, "[", "src/(", "x{2,1}"} {
+		if _, err := compileIgnoreLine(line); err == nil {
+			t.Errorf("compileIgnoreLine(%q) succeeded, want an error", line)
+		}
+	}
+	for _, line := range []string{"*.log", "/build/", "[abc].go", `\#notes`} {
+		if _, err := compileIgnoreLine(line); err != nil {
+			t.Errorf("compileIgnoreLine(%q): %s", line, err)
+		}
+	}
+}
+
+func TestMalformedNestedIgnoreFile(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore":            "*.tmp.go\n",
+		"main.go":               "// AES\n",
+		"skip.tmp.go":           "// RC4\n",
+		"vendor/lib/.gitignore": "foo\\\n[\nsecret.go\n",
+		"vendor/lib/lib.go":     "// MD5\n",
+		"vendor/lib/secret.go":  "// DES\n",
+	})
+	got := found(scan(t, newTestScanner(), dir))
+	// The malformed lines are skipped and the rest of the file still applies
+	want := []string{"main.go:1 AES", "vendor/lib/lib.go:1 MD5"}
+	if !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+func TestNestedIgnoreFile(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore":          "*.tmp.go\n",
+		"main.go":             "// AES\n",
+		"gen.go":              "// SHA1\n",
+		"sub/.gitignore":      "gen.go\n",
+		"sub/gen.go":          "// RC4\n",
+		"sub/keep.go":         "// MD5\n",
+		"sub/deeper/gen.go":   "// DES\n",
+		"sub/deeper/x.tmp.go": "// 3DES\n",
+	})
+	got := found(scan(t, newTestScanner(), dir))
+	// sub/.gitignore excludes gen.go below sub only, not the root's gen.go
+	want := []string{"gen.go:1 SHA-1", "main.go:1 AES", "sub/keep.go:1 MD5"}
+	if !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+func TestNegatedIgnoreLine(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore":   "*.log\n!keep.log\n",
+		"debug.log":    "cipher: RC4\n",
+		"keep.log":     "cipher: AES\n",
+		"sub/keep.log": "hash: MD5\n",
+		"sub/run.log":  "cipher: DES\n",
+	})
+	s := newTestScanner()
+	s.extensions[".log"] = true
+	got := found(scan(t, s, dir))
+	want := []string{"keep.log:1 AES", "sub/keep.log:1 MD5"}
+	if !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package scan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// joinSpan is the part of a line kept when joining it to the lines it
+// continues: piece is visible[start:end] of the line, without the quotes and
+// operator of the string concatenation.
+type joinSpan struct {
+	no    int    // Line number
+	text  string // Whole line, for the context and fingerprint of a match
+	start int    // Byte offset of piece in the line
+	piece string
+}
+
+// continuedString matches the end of a line whose string literal carries on
+// in the next line: a closing quote, then an optional concatenation operator
+// (+ . .. & ||) and line continuation.
+var continuedString = regexp.MustCompile(`(["'])\s*(?:\+|\.\.?|&|\|\|)?\s*\\?\s*$`)
+
+// continuingString matches the start of a line carrying on the string
+// literal of the previous one.
+var continuingString = regexp.MustCompile(`^\s*(?:\+|\.\.?|&|\|\|)?\s*(["'])`)
+
+// lineWindow joins the lines of a file continuing each other by string
+// concatenation, for -line-window, so that a name split as in
+//
+//	cipher := "Cha" +
+//		"Cha20"
+//
+// is matched as "ChaCha20". A line is joined to at most size-1 lines before
+// it.
+type lineWindow struct {
+	size  int
+	spans []joinSpan // The lines joined so far, oldest first
+	prev  string     // Visible text of the previous line
+}
+
+// add joins the line numbered no, with the given whole and visible text, to
+// the lines before it. It returns the spans of the joined lines, or nil if
+// the line does not continue the previous one.
+func (w *lineWindow) add(no int, text, visible string) []joinSpan {
+	prev := w.prev
+	w.prev = visible
+	span := joinSpan{no: no, text: text, piece: visible}
+	if len(w.spans) > 0 {
+		end := continuedString.FindStringSubmatchIndex(prev)
+		start := continuingString.FindStringSubmatchIndex(visible)
+		last := &w.spans[len(w.spans)-1]
+		switch {
+		case end != nil && start != nil && prev[end[2]] == visible[start[2]]:
+			// "Cha" + / "Cha20": drop the quotes and the operator between
+			last.piece = last.piece[:max(end[2]-last.start, 0)]
+			span.start = start[3]
+		case strings.HasSuffix(prev, `\`) && strings.Count(prev, `"`)%2 == 1:
+			// "Cha\ / Cha20": a C string continued by a backslash
+			last.piece = strings.TrimSuffix(last.piece, `\`)
+		default:
+			w.spans = w.spans[:0]
+		}
+	}
+	span.piece = visible[span.start:]
+	w.spans = append(w.spans, span)
+	if len(w.spans) > w.size {
+		w.spans = append(w.spans[:0], w.spans[1:]...)
+	}
+	if len(w.spans) < 2 {
+		return nil
+	}
+	return append([]joinSpan(nil), w.spans...)
+}
+
+// reset starts over after a line that was not scanned.
+func (w *lineWindow) reset() {
+	w.spans = w.spans[:0]
+}
+
+// crossLine runs the detectors over the joined lines and appends to matches
+// those spanning a line break into the last of them. Matches within a single
+// line are found on that line, and those ending in an earlier line were
+// returned for it, so each name is reported once, at the line where it
+// starts. lineMatches are the matches found on the last line alone; those
+// overlapping the end of a longer name, such as "Poly1305" in "Cha" +
+// "Cha20-Poly1305", are dropped.
+func (lm *lineMatcher) crossLine(spans []joinSpan, matches, lineMatches []Match) []Match {
+	if lm.styleFile {
+		return append(matches, lineMatches...) // Short tokens only count in per-line regions there
+	}
+	var joined strings.Builder
+	offsets := make([]int, len(spans)) // Offset of each piece in joined
+	for i, span := range spans {
+		offsets[i] = joined.Len()
+		joined.WriteString(span.piece)
+	}
+	lastStart := offsets[len(offsets)-1]
+
+	var found []Match
+	end := 0 // Byte offset in the last line where the names found end
+	for _, f := range detectAll(lm.detectors, joined.String()) {
+		if f.Start >= lastStart || f.End <= lastStart {
+			continue
+		}
+		i := len(offsets) - 1
+		for offsets[i] > f.Start {
+			i--
+		}
+		if f.Severity == "" {
+			f.Severity = severityOf(f.Algorithm)
+		}
+		span := spans[i]
+		found = append(found, lm.newMatch(f.Algorithm, f.Severity, span.no, span.text, span.start+f.Start-offsets[i]))
+		end = max(end, spans[len(spans)-1].start+f.End-lastStart)
+	}
+	for _, m := range lineMatches {
+		if m.Col > end {
+			matches = append(matches, m)
+		}
+	}
+	return append(matches, lm.filter(found, joined.String())...)
+}
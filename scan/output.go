@@ -0,0 +1,541 @@
+package scan
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// usageNote returns the text report's annotation for a match's usage.
+func usageNote(m Match) string {
+	if m.Usage == "" {
+		return ""
+	}
+	return " (likely " + m.Usage + ")"
+}
+
+// sourceNote returns the text report's annotation for a match found in
+// documentation.
+func sourceNote(m Match) string {
+	if m.Source != "doc" {
+		return ""
+	}
+	return " (documentation)"
+}
+
+// blameNote returns the text report's annotation for the author,
+// abbreviated commit and day of the commit of a match, set with -git-blame.
+func blameNote(m Match) string {
+	if m.Commit == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s, %.7s, %.10s]", m.Author, m.Commit, m.Date)
+}
+
+// summaryLine returns the one-line summary written to stderr at the end of
+// a run whatever the -format, for log monitoring: key=value pairs, always
+// all of them and in this order, such as
+//
+//	dumpvars: files=120 findings=7 weak=2 deprecated=1 ok=4 errors=0 elapsed=1.520s exit=2
+//
+// findings counts the matches reported, at or above -severity-min; errors
+// counts the paths that could not be scanned.
+func (s *scanner) summaryLine(elapsed time.Duration, code int) string {
+	counts := s.written
+	if counts == nil {
+		counts = make(map[string]int)
+		for _, m := range s.matches {
+			counts[m.Severity]++
+		}
+	}
+	return fmt.Sprintf("dumpvars: files=%d findings=%d weak=%d deprecated=%d ok=%d errors=%d elapsed=%.3fs exit=%d",
+		s.filesScanned, counts["weak"]+counts["deprecated"]+counts["ok"], counts["weak"], counts["deprecated"], counts["ok"],
+		len(s.scanErrors), elapsed.Seconds(), code)
+}
+
+// printText writes the human-readable report to stdout.
+func (s *scanner) printText(reportQuantum, byFile bool) {
+	byAlgorithm := groupByAlgorithm(s.matches)
+	shown := s.shownMatches()
+	if byFile {
+		files, byPath := groupByFile(shown)
+		fmt.Println("Files with algorithms found:")
+		for _, file := range files {
+			matches := byPath[file]
+			fmt.Printf("- %s: %s\n", file, plural(len(matches), "occurrence"))
+			for _, m := range matches {
+				fmt.Printf("    %d:%d %s [%s, %s]%s%s%s\n", m.Line, m.Col, m.Algorithm, m.Severity, m.Category, usageNote(m), sourceNote(m), blameNote(m))
+				if m.Context != "" {
+					fmt.Printf("        %s\n", excerpt(m.Context, m.contextCol, s.contextWidth))
+				}
+			}
+		}
+		if s.hints {
+			printHints(sortedAlgorithms(groupByAlgorithm(shown)))
+		}
+	} else {
+		fmt.Println("Unique algorithms found:")
+		shownByAlgorithm := groupByAlgorithm(shown)
+		for _, alg := range sortedAlgorithms(shownByAlgorithm) {
+			matches := shownByAlgorithm[alg]
+			fmt.Printf("- %s [%s, %s]: %s across %s\n", alg, groupSeverity(matches), matches[0].Category,
+				plural(len(matches), "occurrence"), plural(len(uniqueFiles(matches)), "file"))
+			if hint := s.remediation(alg); hint != "" {
+				fmt.Printf("  Remediation: %s\n", hint)
+			}
+			for _, m := range matches {
+				fmt.Printf("    %s:%d:%d%s%s%s\n", m.Path(), m.Line, m.Col, usageNote(m), sourceNote(m), blameNote(m))
+				if m.Context != "" {
+					fmt.Printf("        %s\n", excerpt(m.Context, m.contextCol, s.contextWidth))
+				}
+			}
+		}
+	}
+
+	if omitted := len(s.matches) - len(shown); omitted > 0 {
+		which := "most"
+		if s.tail > 0 {
+			which = "least"
+		}
+		fmt.Printf("Omitted %s; listed the %d %s severe of %d\n", plural(omitted, "finding"), len(shown), which, len(s.matches))
+	}
+
+	if reportQuantum {
+		fmt.Println("Quantum-vulnerable algorithms found:")
+		for _, alg := range sortedAlgorithms(byAlgorithm) {
+			if byAlgorithm[alg][0].QuantumRisk == "vulnerable" {
+				fmt.Println("-", alg)
+			}
+		}
+	}
+
+	if s.fips {
+		violations := fipsViolations(byAlgorithm)
+		if len(violations) == 0 {
+			fmt.Println("FIPS 140-2: no violations found")
+		} else {
+			fmt.Println("FIPS 140-2 violations:")
+		}
+		for _, alg := range violations {
+			matches := byAlgorithm[alg]
+			fmt.Printf("- %s [%s]: %s across %s\n", alg, fipsStatusOf(alg),
+				plural(len(matches), "occurrence"), plural(len(uniqueFiles(matches)), "file"))
+		}
+	}
+
+	if s.stopped.Load() {
+		fmt.Printf("Report truncated at %s (-max-findings)\n", plural(s.maxFindings, "finding"))
+	}
+
+	if s.skipLinesOver > 0 {
+		fmt.Printf("Lines skipped (longer than %d characters): %d\n", s.skipLinesOver, s.linesSkipped)
+	}
+
+	if s.dedupContent {
+		fmt.Printf("Duplicate files skipped (-dedup-content): %d\n", s.duplicates)
+	}
+
+	if s.stats != nil {
+		fmt.Println("Scanned by extension:")
+		exts := make([]string, 0, len(s.stats))
+		for ext := range s.stats {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		for _, ext := range exts {
+			fmt.Printf("- %s: %s, %s\n", ext, plural(s.stats[ext].Files, "file"), plural(s.stats[ext].Lines, "line"))
+		}
+	}
+
+	if summary := s.rootSummary(); summary != nil {
+		printRoots(summary)
+	}
+
+	if len(s.scanErrors) > 0 {
+		fmt.Printf("Could not scan %s:\n", plural(len(s.scanErrors), "path"))
+		for _, e := range s.scanErrors {
+			fmt.Printf("- %s: %s\n", e.Path, e.Error)
+		}
+	}
+
+	if s.showClean {
+		fmt.Printf("Scanned with no findings: %s\n", plural(len(s.clean), "file"))
+		for _, path := range s.clean {
+			fmt.Println("-", path)
+		}
+	}
+}
+
+// fileStats counts the files and lines scanned with one extension.
+type fileStats struct {
+	Files int `json:"files"`
+	Lines int `json:"lines"`
+}
+
+// noExtension is the -stats key for files without an extension.
+const noExtension = "(none)"
+
+// uniqueFiles returns the distinct display paths of matches in order of
+// first appearance.
+func uniqueFiles(matches []Match) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if !seen[m.Path()] {
+			seen[m.Path()] = true
+			files = append(files, m.Path())
+		}
+	}
+	return files
+}
+
+// plural formats a count with a noun, adding an "s" unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// writeCSV writes one row per match to w, preceded by a header row.
+func (s *scanner) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"algorithm", "file", "line", "column", "severity", "category", "usage", "source", "author", "commit", "date"})
+	for _, m := range s.matches {
+		writer.Write([]string{m.Algorithm, m.Path(), strconv.Itoa(m.Line), strconv.Itoa(m.Col), m.Severity, m.Category, m.Usage, m.Source, m.Author, m.Commit, m.Date})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeTable writes one row per algorithm to w, in the order of the text
+// report, with its columns aligned. Like the text report it lists only the
+// matches kept by -head or -tail, noting how many were left out.
+func (s *scanner) writeTable(w io.Writer) error {
+	shown := s.shownMatches()
+	byAlgorithm := groupByAlgorithm(shown)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ALGORITHM\tCOUNT\tFILES\tSEVERITY\tCATEGORY")
+	for _, alg := range sortedAlgorithms(byAlgorithm) {
+		matches := byAlgorithm[alg]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", alg, len(matches), len(uniqueFiles(matches)), groupSeverity(matches), matches[0].Category)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if omitted := len(s.matches) - len(shown); omitted > 0 {
+		fmt.Fprintf(w, "Omitted %s\n", plural(omitted, "finding"))
+	}
+	if len(s.scanErrors) > 0 {
+		fmt.Fprintf(w, "Could not scan %s; see -format text\n", plural(len(s.scanErrors), "path"))
+	}
+	return nil
+}
+
+// contextWidth is the number of characters of context shown per match when
+// stdout is not a terminal.
+const contextWidth = 120
+
+// contextIndent is the indentation printText puts before a context line.
+const contextIndent = 8
+
+// defaultContextWidth returns the -context-width default: what fits the
+// terminal after the indentation when stdout is one, contextWidth otherwise.
+func defaultContextWidth() int {
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return contextWidth
+	}
+	columns, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || columns <= 0 {
+		columns = terminalWidth(os.Stdout)
+	}
+	if columns-contextIndent < 20 {
+		return contextWidth
+	}
+	return columns - contextIndent
+}
+
+// excerpt shortens text to at most width runes, keeping the rune at offset
+// center in view and marking each cut with "...". A width of 0 or less
+// leaves text whole.
+func excerpt(text string, center, width int) string {
+	runes := []rune(text)
+	if width <= 0 || len(runes) <= width {
+		return text
+	}
+	const ellipsis = "..."
+	if width <= 2*len(ellipsis) {
+		return string(runes[:width])
+	}
+	start := max(0, min(center-width/2, len(runes)-width))
+	end := start + width
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = ellipsis
+		start += len(ellipsis)
+	}
+	if end < len(runes) {
+		suffix = ellipsis
+		end -= len(ellipsis)
+	}
+	return prefix + string(runes[start:end]) + suffix
+}
+
+// jsonSchemaVersion is the schemaVersion of the -format json document and of
+// every -format jsonl line. It is raised whenever a field is removed or
+// renamed or its meaning changes; fields may be added without raising it, so
+// consumers should ignore fields they do not know.
+const jsonSchemaVersion = 2
+
+// jsonReport is the document written by -json.
+type jsonReport struct {
+	SchemaVersion int             `json:"schemaVersion"` // See jsonSchemaVersion
+	Summary       jsonSummary     `json:"summary"`
+	Algorithms    []jsonAlgorithm `json:"algorithms"`
+
+	// With -fips, every algorithm that is not FIPS 140-2 approved
+	FIPSViolations []jsonFIPSViolation `json:"fipsViolations,omitempty"`
+
+	// Files and directories that could not be scanned in full
+	Errors []scanError `json:"errors,omitempty"`
+}
+
+// jsonFIPSViolation is an algorithm that is not FIPS 140-2 approved, with
+// -fips.
+type jsonFIPSViolation struct {
+	Algorithm string   `json:"algorithm"`
+	Status    string   `json:"status"` // "non-approved" or "disallowed"
+	Files     []string `json:"files"`
+	Count     int      `json:"count"`
+}
+
+// jsonSummary describes the scan as a whole.
+type jsonSummary struct {
+	Directories  []string `json:"directories"`
+	FilesScanned int      `json:"filesScanned"`
+	LinesSkipped int      `json:"linesSkipped,omitempty"`
+	Duplicates   int      `json:"duplicates,omitempty"` // Files not scanned for having the content of another, with -dedup-content
+	Truncated    bool     `json:"truncated,omitempty"`  // -max-findings stopped the scan
+
+	Roots []*rootStats `json:"roots,omitempty"` // With several roots, the files and findings of each
+
+	ByExtension map[string]*fileStats `json:"byExtension,omitempty"`
+	CleanFiles  []string              `json:"cleanFiles,omitempty"`
+}
+
+// jsonAlgorithm is a reported algorithm with every file it was found in.
+type jsonAlgorithm struct {
+	Algorithm string   `json:"algorithm"`
+	Severity  string   `json:"severity"`
+	Category  string   `json:"category"`
+	Files     []string `json:"files"`
+	Count     int      `json:"count"`
+
+	NonSecurity int      `json:"nonSecurity,omitempty"` // Matches marked by -non-security-hints
+	InDocs      int      `json:"inDocs,omitempty"`      // Matches in documentation files
+	Authors     []string `json:"authors,omitempty"`     // Distinct authors of the matched lines, with -git-blame
+	Remediation string   `json:"remediation,omitempty"` // Recommended replacement, with -hints
+}
+
+// jsonMatch is a single match as written by -format jsonl.
+type jsonMatch struct {
+	SchemaVersion int    `json:"schemaVersion"` // See jsonSchemaVersion
+	Algorithm     string `json:"algorithm"`
+	Severity      string `json:"severity"`
+	Category      string `json:"category"`
+	QuantumRisk   string `json:"quantumRisk"`
+	File          string `json:"file"`
+	Line          int    `json:"line"`
+	Column        int    `json:"column"`
+	Context       string `json:"context,omitempty"`
+	Usage         string `json:"usage,omitempty"`
+	Source        string `json:"source"`
+	Author        string `json:"author,omitempty"`
+	Commit        string `json:"commit,omitempty"`
+	Date          string `json:"date,omitempty"`        // Author date of Commit
+	Remediation   string `json:"remediation,omitempty"` // Recommended replacement, with -hints
+}
+
+// streamMatches writes the matches of one scanned file to s.stream, one JSON
+// object per line. It is called with s.mu held, so the lines of concurrent
+// workers never interleave. Matches below -severity-min are not written but
+// their severity still counts for -fail-on.
+func (s *scanner) streamMatches(matches []Match) {
+	for _, m := range matches {
+		s.streamed[m.Severity] = true
+		if severityLevels[m.Severity] < s.streamMin || s.streamErr != nil {
+			continue
+		}
+		s.streamErr = s.stream.Encode(jsonMatch{
+			SchemaVersion: jsonSchemaVersion,
+			Algorithm:     m.Algorithm,
+			Severity:      m.Severity,
+			Category:      m.Category,
+			QuantumRisk:   m.QuantumRisk,
+			File:          m.Path(),
+			Line:          m.Line,
+			Column:        m.Col,
+			Context:       m.Context,
+			Usage:         m.Usage,
+			Source:        m.Source,
+			Author:        m.Author,
+			Commit:        m.Commit,
+			Date:          m.Date,
+			Remediation:   s.remediation(m.Algorithm),
+		})
+		s.written[m.Severity]++
+	}
+}
+
+// writeJSON writes the results as a single JSON document to w.
+func (s *scanner) writeJSON(w io.Writer, dirs []string) error {
+	report := jsonReport{
+		SchemaVersion: jsonSchemaVersion,
+		Summary: jsonSummary{
+			Directories:  dirs,
+			FilesScanned: s.filesScanned,
+			LinesSkipped: s.linesSkipped,
+			Duplicates:   s.duplicates,
+			Truncated:    s.stopped.Load(),
+			ByExtension:  s.stats,
+			CleanFiles:   s.clean,
+			Roots:        s.rootSummary(),
+		},
+		Algorithms: []jsonAlgorithm{},
+	}
+	byAlgorithm := groupByAlgorithm(s.matches)
+	for _, alg := range sortedAlgorithms(byAlgorithm) {
+		matches := byAlgorithm[alg]
+		entry := jsonAlgorithm{
+			Algorithm: alg,
+			Severity:  groupSeverity(matches),
+			Category:  matches[0].Category,
+			Files:     uniqueFiles(matches),
+			Count:     len(matches),
+
+			Remediation: s.remediation(alg),
+		}
+		authors := make(map[string]bool)
+		for _, m := range matches {
+			if m.Usage == "non-security" {
+				entry.NonSecurity++
+			}
+			if m.Source == "doc" {
+				entry.InDocs++
+			}
+			if m.Author != "" && !authors[m.Author] {
+				authors[m.Author] = true
+				entry.Authors = append(entry.Authors, m.Author)
+			}
+		}
+		report.Algorithms = append(report.Algorithms, entry)
+	}
+	report.Errors = s.scanErrors
+	if s.fips {
+		for _, alg := range fipsViolations(byAlgorithm) {
+			report.FIPSViolations = append(report.FIPSViolations, jsonFIPSViolation{
+				Algorithm: alg,
+				Status:    fipsStatusOf(alg),
+				Files:     uniqueFiles(byAlgorithm[alg]),
+				Count:     len(byAlgorithm[alg]),
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// filterSeverity returns the matches whose severity is at least minLevel.
+func filterSeverity(matches []Match, minLevel int) []Match {
+	var kept []Match
+	for _, m := range matches {
+		if severityLevels[m.Severity] >= minLevel {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// groupByAlgorithm groups matches under the algorithm name they matched,
+// preserving their order within each group.
+func groupByAlgorithm(matches []Match) map[string][]Match {
+	groups := make(map[string][]Match)
+	for _, m := range matches {
+		groups[m.Algorithm] = append(groups[m.Algorithm], m)
+	}
+	return groups
+}
+
+// sortedAlgorithms returns the algorithm names of groups in alphabetical
+// order, so that reports do not depend on map iteration order.
+func sortedAlgorithms(groups map[string][]Match) []string {
+	algs := make([]string, 0, len(groups))
+	for alg := range groups {
+		algs = append(algs, alg)
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// sortMatches orders matches by path, line, column and algorithm. Workers
+// finish files in no particular order, so matches are sorted once the scan
+// is done to make the output of repeated runs identical.
+func sortMatches(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if pa, pb := a.Path(), b.Path(); pa != pb {
+			return pa < pb
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Col != b.Col {
+			return a.Col < b.Col
+		}
+		return a.Algorithm < b.Algorithm
+	})
+}
+
+// shownMatches returns the matches listed by the text report: all of them,
+// or with -head or -tail the first or last N in order of decreasing
+// severity. Matches of equal severity are taken in sortMatches order, which
+// the returned matches keep.
+func (s *scanner) shownMatches() []Match {
+	n := max(s.head, s.tail)
+	if n <= 0 || len(s.matches) <= n {
+		return s.matches
+	}
+	ranked := append([]Match(nil), s.matches...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return severityLevels[ranked[i].Severity] > severityLevels[ranked[j].Severity]
+	})
+	if s.head > 0 {
+		ranked = ranked[:n]
+	} else {
+		ranked = ranked[len(ranked)-n:]
+	}
+	sortMatches(ranked)
+	return ranked
+}
+
+// groupByFile groups matches by the display path of the file they were found
+// in, returning the paths in order of first appearance.
+func groupByFile(matches []Match) ([]string, map[string][]Match) {
+	groups := make(map[string][]Match)
+	var files []string
+	for _, m := range matches {
+		if _, ok := groups[m.Path()]; !ok {
+			files = append(files, m.Path())
+		}
+		groups[m.Path()] = append(groups[m.Path()], m)
+	}
+	return files, groups
+}
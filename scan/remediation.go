@@ -0,0 +1,91 @@
+package scan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// remediations are the replacements recommended by -hints for weak and
+// deprecated algorithms, keyed by upper-case base name like severities.
+// Undersized keys, insecure modes and the categories without a fixed name
+// are handled by remediationOf.
+var remediations = map[string]string{
+	"MD5":      "Replace MD5 with SHA-256 for integrity; use HMAC-SHA-256 for authentication and Argon2 or bcrypt for passwords.",
+	"SHA1":     "Replace SHA-1 with SHA-256 for integrity; use HMAC-SHA-256 for authentication and Argon2 or bcrypt for passwords.",
+	"DES":      "Replace DES with AES-256-GCM or ChaCha20-Poly1305.",
+	"3DES":     "Replace 3DES with AES-256-GCM or ChaCha20-Poly1305.",
+	"RC2":      "Replace RC2 with AES-256-GCM or ChaCha20-Poly1305.",
+	"RC4":      "Replace RC4 with AES-256-GCM or ChaCha20-Poly1305.",
+	"RC5":      "Replace RC5 with AES-256-GCM or ChaCha20-Poly1305.",
+	"BLOWFISH": "Replace Blowfish with AES-256-GCM or ChaCha20-Poly1305; its 64-bit blocks are open to birthday attacks on long streams.",
+	"GOST":     "Replace GOST 28147-89 with AES-256-GCM, or with Kuznyechik where GOST is required.",
+	"DSA":      "Replace DSA with Ed25519 or ECDSA over P-256.",
+	"SSL 2.0":  "Disable SSL 2.0 and require TLS 1.2 or later, preferably TLS 1.3.",
+	"SSL 3.0":  "Disable SSL 3.0 and require TLS 1.2 or later, preferably TLS 1.3.",
+	"TLS 1.0":  "Disable TLS 1.0 and require TLS 1.2 or later, preferably TLS 1.3.",
+	"TLS 1.1":  "Disable TLS 1.1 and require TLS 1.2 or later, preferably TLS 1.3.",
+}
+
+// remediationOf returns the -hints recommendation for a reported algorithm
+// name, or "" if there is nothing to replace it with.
+func remediationOf(alg string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(alg), " "))
+	base, bits := splitAlgorithm(name)
+	if minimum, ok := minimumKeySizes[base]; ok && bits > 0 && bits < minimum {
+		return fmt.Sprintf("Use keys of at least %d bits.", minimum)
+	}
+	for _, part := range strings.Split(name, "-") {
+		if part == "ECB" {
+			return ecbRemediation
+		}
+	}
+	if strings.HasPrefix(base, "SHA") {
+		if severityOf(alg) == "ok" {
+			return ""
+		}
+		base = "SHA1" // The only weak SHA, reported as SHA-1 or SHA1
+	}
+	if hint, ok := remediations[base]; ok {
+		return hint
+	}
+	switch categoryOf(alg) {
+	case "rng":
+		return "Use a cryptographically secure generator, such as crypto/rand, secrets or SecureRandom, for keys, tokens and nonces."
+	case "custom-crypto":
+		return "Replace the hand-rolled cipher with a vetted authenticated cipher such as AES-256-GCM."
+	case "jwt":
+		if strings.HasSuffix(name, "NONE") {
+			return "Reject unsigned tokens: never accept alg none, and pin the algorithms a verifier accepts."
+		}
+		return "Use a random HMAC secret of at least 256 bits that only token issuers hold; prefer RS256, ES256 or EdDSA when other services verify tokens."
+	case "key-material":
+		return "Move keys and certificates out of the source tree into a secret store, and rotate any that were committed."
+	}
+	return ""
+}
+
+// remediation returns remediationOf(alg) with -hints, or "" without.
+func (s *scanner) remediation(alg string) string {
+	if !s.hints {
+		return ""
+	}
+	return remediationOf(alg)
+}
+
+// printHints writes the -hints recommendation of each algorithm in algs
+// that has one.
+func printHints(algs []string) {
+	var lines []string
+	for _, alg := range algs {
+		if hint := remediationOf(alg); hint != "" {
+			lines = append(lines, fmt.Sprintf("- %s: %s", alg, hint))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Println("Remediation hints:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,67 @@
+package scan
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// scpLikeURL matches the scp-like addresses git accepts for ssh, such as
+// git@github.com:owner/repo.git.
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/\\]`)
+
+// isGitURL reports whether arg names a git repository to clone rather than
+// a path: an http, https, ssh, git or file URL, or an scp-like address. A
+// path that exists is always a path.
+func isGitURL(arg string) bool {
+	if _, err := os.Lstat(arg); err == nil {
+		return false
+	}
+	for _, scheme := range []string{"https://", "http://", "ssh://", "git+ssh://", "git://", "file://"} {
+		if strings.HasPrefix(arg, scheme) {
+			return true
+		}
+	}
+	return scpLikeURL.MatchString(arg)
+}
+
+// scanRemote makes a shallow clone of the repository at url in a temporary
+// directory and walks it, reporting the files under url. Authentication is
+// left to git, so credential helpers, ssh agents and prompts work as they
+// do for git clone. The clone is removed by removeClones once its files are
+// scanned.
+func (s *scanner) scanRemote(url string) {
+	dir, err := os.MkdirTemp("", "dumpvars-clone-")
+	if err != nil {
+		s.errorf(url, "cloning %s: %w", url, err)
+		return
+	}
+	s.mu.Lock()
+	s.clones = append(s.clones, dir)
+	s.mu.Unlock()
+
+	s.verbosef("Cloning %s\n", url)
+	cmd := exec.Command("git", "clone", "--depth", "1", "--quiet", "--", url, dir)
+	if _, err := cmd.Output(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			err = errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		s.errorf(url, "cloning %s: %w", url, err)
+		return
+	}
+	s.walkRoot(url, dir, strings.TrimSuffix(url, "/"))
+}
+
+// removeClones deletes the clones made by scanRemote. It is called once the
+// workers are done with their files.
+func (s *scanner) removeClones() {
+	for _, dir := range s.clones {
+		if err := os.RemoveAll(dir); err != nil {
+			s.warnf(dir, "could not remove clone %s: %w", dir, err)
+		}
+	}
+	s.clones = nil
+}
@@ -0,0 +1,63 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitCommits creates a git repository in a new temporary directory with one
+// commit per element of commits, each adding the files it maps, and returns
+// the directory. It skips the test if git is not installed.
+func gitCommits(t *testing.T, commits ...map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	for i, files := range commits {
+		for name, content := range files {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", "commit "+string(rune('1'+i)))
+	}
+	return dir
+}
+
+func TestSince(t *testing.T) {
+	dir := gitCommits(t,
+		map[string]string{"old.go": "// MD5\n"},
+		map[string]string{"new.go": "// AES\n"})
+	code, out := runArgs(t, "-since", "HEAD~1", dir)
+	if code != exitOK || !strings.Contains(out, "new.go") || strings.Contains(out, "old.go") {
+		t.Errorf("exit %d, want 0 and only new.go reported:\n%s", code, out)
+	}
+}
+
+func TestSinceSkippedForClone(t *testing.T) {
+	dir := gitCommits(t,
+		map[string]string{"old.go": "// MD5\n"},
+		map[string]string{"new.go": "// AES\n"})
+	// The clone is shallow, so HEAD~1 does not exist in it
+	code, out := runArgs(t, "-since", "HEAD~1", "file://"+dir)
+	if code != exitOK || !strings.Contains(out, "new.go") || !strings.Contains(out, "old.go") {
+		t.Errorf("exit %d, want 0 and the whole clone reported:\n%s", code, out)
+	}
+}
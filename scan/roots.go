@@ -0,0 +1,128 @@
+package scan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// rootStats counts the files scanned and the findings reported under one
+// scan root, for the per-root summary shown when several roots are scanned.
+type rootStats struct {
+	Root         string         `json:"root"`
+	FilesScanned int            `json:"filesScanned"`
+	Findings     int            `json:"findings"`
+	Severities   map[string]int `json:"severities,omitempty"` // Findings per severity
+}
+
+// scanRoots walks the directories and files in roots, at most
+// s.parallelRoots of them at once. Every walk feeds the same worker pool, so
+// files are scanned concurrently whatever the number of roots walked.
+func (s *scanner) scanRoots(roots []string) {
+	if len(roots) > 1 {
+		s.byRoot = make(map[string]*rootStats)
+	}
+	if s.parallelRoots < 1 || s.listFiles {
+		s.parallelRoots = 1 // -list-files prints in walk order
+	}
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, s.parallelRoots)
+	for _, dir := range roots {
+		limit <- struct{}{}
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			defer func() { <-limit }()
+			s.scanDir(dir)
+		}(dir)
+	}
+	wg.Wait()
+}
+
+// addRoot records key, the display path of a root, for the per-root
+// summary, so that roots without any file scanned are listed too.
+func (s *scanner) addRoot(key string) {
+	if s.byRoot == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byRoot[key] == nil {
+		s.byRoot[key] = &rootStats{Root: key}
+	}
+}
+
+// rootKey returns the key of the root a file was found under: the display
+// path of the directory or archive, or for a file named on the command line,
+// the file itself.
+func rootKey(root, name string) string {
+	if root == "" {
+		return name
+	}
+	return strings.TrimSuffix(root, archiveSeparator)
+}
+
+// rootSummary counts the reported matches of each root and returns the
+// per-root statistics sorted by root, or nil unless several roots were
+// scanned.
+func (s *scanner) rootSummary() []*rootStats {
+	if s.byRoot == nil {
+		return nil
+	}
+	for _, stats := range s.byRoot {
+		stats.Findings, stats.Severities = 0, nil
+	}
+	for _, m := range s.matches {
+		stats := s.byRoot[rootKey(m.Root, m.File)]
+		if stats == nil {
+			continue
+		}
+		if stats.Severities == nil {
+			stats.Severities = make(map[string]int)
+		}
+		stats.Findings++
+		stats.Severities[m.Severity]++
+	}
+	summary := make([]*rootStats, 0, len(s.byRoot))
+	for _, stats := range s.byRoot {
+		summary = append(summary, stats)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Root < summary[j].Root })
+	return summary
+}
+
+// severityCounts formats the findings of each severity, most severe first,
+// such as "2 weak, 1 ok".
+func severityCounts(counts map[string]int) string {
+	var parts []string
+	for _, severity := range []string{"weak", "deprecated", "ok"} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printRoots writes the per-root summary and the grand total to stdout.
+func printRoots(summary []*rootStats) {
+	fmt.Println("Scanned by root:")
+	total := rootStats{Severities: make(map[string]int)}
+	for _, stats := range summary {
+		fmt.Printf("- %s: %s, %s", stats.Root, plural(stats.FilesScanned, "file"), plural(stats.Findings, "finding"))
+		if stats.Findings > 0 {
+			fmt.Printf(" (%s)", severityCounts(stats.Severities))
+		}
+		fmt.Println()
+		total.FilesScanned += stats.FilesScanned
+		total.Findings += stats.Findings
+		for severity, n := range stats.Severities {
+			total.Severities[severity] += n
+		}
+	}
+	fmt.Printf("Total: %s, %s", plural(total.FilesScanned, "file"), plural(total.Findings, "finding"))
+	if total.Findings > 0 {
+		fmt.Printf(" (%s)", severityCounts(total.Severities))
+	}
+	fmt.Println()
+}
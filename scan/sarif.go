@@ -0,0 +1,186 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// The subset of SARIF 2.1.0 written by -format sarif, enough for GitHub code
+// scanning to show each match as an alert on the matched line.
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+// sarifInvocation reports whether the scan completed, with a notification
+// for each file or directory that could not be scanned in full.
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string        `json:"id"`   // sarifRuleID of the algorithm
+	Name                 string        `json:"name"` // The algorithm as reported
+	ShortDescription     sarifMessage  `json:"shortDescription"`
+	Help                 *sarifMessage `json:"help,omitempty"` // Remediation, with -hints
+	DefaultConfiguration sarifConfig   `json:"defaultConfiguration"`
+	Properties           sarifProps    `json:"properties"`
+}
+
+// sarifProps carries the category of a rule as a tag, which GitHub code
+// scanning shows and filters on.
+type sarifProps struct {
+	Tags []string `json:"tags"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// sarifLevels maps severities to SARIF result levels.
+var sarifLevels = map[string]string{
+	"ok":         "note",
+	"deprecated": "warning",
+	"weak":       "error",
+}
+
+// sarifRuleID returns the rule ID of an algorithm, a slug free of spaces and
+// capitals as code scanning tools expect, such as "crypto/ssl-3.0" for
+// "SSL 3.0" and "crypto/aes" for "AES". The severity is left to the rule's
+// level, so that an ID does not change when a -config pattern changes it.
+func sarifRuleID(alg string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, alg)
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	return "crypto/" + strings.Trim(slug, "-")
+}
+
+// sarifUsage qualifies the result message of a match with its usage.
+func sarifUsage(m Match) string {
+	if m.Usage == "" {
+		return ""
+	}
+	return ", likely " + m.Usage
+}
+
+// writeSARIF writes the matches to w as a SARIF 2.1.0 log with one rule per
+// algorithm and one result per match.
+func (s *scanner) writeSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "dumpvars",
+			InformationURI: "https://github.com/sbtaylor15/dumpvars",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	invocation := sarifInvocation{ExecutionSuccessful: !s.failed}
+	for _, e := range s.scanErrors {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, sarifNotification{
+			Level:   "error",
+			Message: sarifMessage{Text: fmt.Sprintf("Could not scan %s: %s", e.Path, e.Error)},
+		})
+	}
+	run.Invocations = []sarifInvocation{invocation}
+
+	rules := make(map[string]bool)
+	for _, m := range s.matches {
+		level := sarifLevels[m.Severity]
+		if !rules[m.Algorithm] {
+			rules[m.Algorithm] = true
+			rule := sarifRule{
+				ID:                   sarifRuleID(m.Algorithm),
+				Name:                 m.Algorithm,
+				ShortDescription:     sarifMessage{Text: fmt.Sprintf("Use of %s", m.Algorithm)},
+				DefaultConfiguration: sarifConfig{Level: level},
+				Properties:           sarifProps{Tags: []string{m.Category}},
+			}
+			if hint := s.remediation(m.Algorithm); hint != "" {
+				rule.Help = &sarifMessage{Text: hint}
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  sarifRuleID(m.Algorithm),
+			Level:   level,
+			Message: sarifMessage{Text: fmt.Sprintf("%s (%s%s) found", m.Algorithm, m.Severity, sarifUsage(m))},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(m.Path())},
+				Region:           sarifRegion{StartLine: m.Line, StartColumn: m.Col},
+			}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
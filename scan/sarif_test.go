@@ -0,0 +1,50 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSARIFRuleID(t *testing.T) {
+	tests := map[string]string{
+		"SSL 3.0":        "crypto/ssl-3.0",
+		"AES-256-GCM":    "crypto/aes-256-gcm",
+		"MD5":            "crypto/md5",
+		"Elliptic Curve": "crypto/elliptic-curve",
+		"TLS 1.0":        "crypto/tls-1.0",
+		"JWT alg none":   "crypto/jwt-alg-none",
+		"AES":            "crypto/aes",
+	}
+	for alg, want := range tests {
+		if got := sarifRuleID(alg); got != want {
+			t.Errorf("sarifRuleID(%q) = %q, want %q", alg, got, want)
+		}
+	}
+}
+
+func TestWriteSARIFRules(t *testing.T) {
+	dir := writeTree(t, map[string]string{"tls.go": "// SSLv3\n// SSL 3.0\n"})
+	s := newTestScanner()
+	scan(t, s, dir)
+	var out bytes.Buffer
+	if err := s.writeSARIF(&out); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	rules := log.Runs[0].Tool.Driver.Rules
+	if len(rules) != 1 || rules[0].ID != "crypto/ssl-3.0" || rules[0].Name != "SSL 3.0" {
+		t.Fatalf("rules = %+v, want one crypto/ssl-3.0 rule named SSL 3.0", rules)
+	}
+	for _, result := range log.Runs[0].Results {
+		if result.RuleID != rules[0].ID {
+			t.Errorf("result ruleId = %q, want %q", result.RuleID, rules[0].ID)
+		}
+	}
+	if len(log.Runs[0].Results) != 2 {
+		t.Errorf("%d results, want 2", len(log.Runs[0].Results))
+	}
+}
@@ -0,0 +1,126 @@
+// Package scan finds the cryptographic algorithms named in source code, with
+// how weak each is. It is the scanner of the dumpvars command: Scan walks
+// files and directories as dumpvars does without flags, and Main is the
+// command itself.
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Option configures a Scan.
+type Option func(*scanner)
+
+// WithPattern makes Scan also report the matches of re, which may use any
+// flags or Unicode classes the regexp package supports, such as
+// (?i)\p{L}*cipher. A match is reported under its canonical name and
+// severity, as a built-in match of the same text would be.
+func WithPattern(re *regexp.Regexp) Option {
+	return WithDetector(regexpDetector{re})
+}
+
+// WithDetector makes Scan also run d on every scanned line, after the
+// built-in detector and those added by earlier options. See Detector for
+// what d must handle.
+func WithDetector(d Detector) Option {
+	return func(s *scanner) {
+		s.detectors = append(s.detectors, d)
+	}
+}
+
+// WithErrorHandler makes Scan pass each error and warning concerning a single
+// file or directory to handle, with the path it is reported under, as it
+// happens. Errors, such as an unreadable file, are those Scan also returns;
+// warnings are passed as a *Warning. Without a handler they are discarded.
+// handle is never called concurrently, but it is called from the goroutines
+// of the scan and holds it up until it returns.
+func WithErrorHandler(handle func(path string, err error)) Option {
+	return func(s *scanner) {
+		s.onError = handle
+	}
+}
+
+// Warning is a problem passed to the handler of WithErrorHandler that did not
+// keep anything from being scanned, such as a malformed ignore file line,
+// which is skipped.
+type Warning struct {
+	Err error
+}
+
+func (w *Warning) Error() string { return w.Err.Error() }
+
+func (w *Warning) Unwrap() error { return w.Err }
+
+// Scan scans the files and directories in roots as dumpvars does without
+// flags and returns the matches, sorted by file and line. Files and roots
+// that could not be scanned in full are left out, and their errors joined,
+// sorted by path, in the error returned alongside the matches found
+// elsewhere.
+func Scan(roots []string, opts ...Option) ([]Match, error) {
+	s := newScanner()
+	for _, opt := range opts {
+		opt(s)
+	}
+	var failures []scanError
+	handle := s.onError
+	s.onError = func(path string, err error) {
+		var warning *Warning
+		if !errors.As(err, &warning) {
+			failures = append(failures, scanError{Path: path, Error: err.Error()})
+		}
+		if handle != nil {
+			handle(path, err)
+		}
+	}
+	s.startWorkers()
+	s.scanRoots(roots)
+	s.stopWorkers()
+	s.removeClones()
+	sortMatches(s.matches)
+
+	sort.SliceStable(failures, func(i, j int) bool { return failures[i].Path < failures[j].Path })
+	var errs []error
+	for _, e := range failures {
+		errs = append(errs, fmt.Errorf("%s: %s", e.Path, e.Error))
+	}
+	return s.matches, errors.Join(errs...)
+}
+
+// ListDetectors describes the detectors Scan runs with opts, in order: the
+// built-in one, then those added by WithPattern and WithDetector. A detector
+// that does not implement DescribedDetector is listed under its Go type.
+func ListDetectors(opts ...Option) []DetectorInfo {
+	s := newScanner()
+	for _, opt := range opts {
+		opt(s)
+	}
+	return describeDetectors(s.detectors)
+}
+
+// newScanner returns a scanner set up as Main sets it up when no flag is
+// given.
+func newScanner() *scanner {
+	s := &scanner{
+		lineWindow:     1,
+		maxSize:        10 << 20,
+		sniffBytes:     defaultSniffBytes,
+		maxDepth:       -1,
+		workers:        runtime.NumCPU(),
+		parallelRoots:  4,
+		threadsPerFile: 1,
+		pathStyle:      "relative",
+		detectors:      []Detector{builtinDetector{}},
+		extensions:     make(map[string]bool),
+		languageOf:     make(map[string]string),
+		ignoreFiles:    strings.Split(defaultIgnoreFiles, ","),
+	}
+	for ext := range validExtensions {
+		s.extensions[ext] = true
+	}
+	return s
+}
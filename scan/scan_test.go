@@ -0,0 +1,210 @@
+package scan_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sbtaylor15/dumpvars/scan"
+)
+
+// writeTree creates files, keyed by slash-separated path, under a new
+// temporary directory and returns the directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// found returns "file:line algorithm" for each of matches.
+func found(matches []scan.Match) []string {
+	var list []string
+	for _, m := range matches {
+		list = append(list, fmt.Sprintf("%s:%d %s", m.File, m.Line, m.Algorithm))
+	}
+	return list
+}
+
+// sameList reports whether got and want hold the same strings in the same
+// order.
+func sameList(got, want []string) bool {
+	return strings.Join(got, "\n") == strings.Join(want, "\n")
+}
+
+func TestScan(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go": "// MD5\n",
+		"b.py": "# AES and SHA1\n",
+	})
+	matches, err := scan.Scan([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.go:1 MD5", "b.py:1 AES", "b.py:1 SHA-1"}
+	if got := found(matches); !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+func TestScanWithPattern(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"hash.go":  "sum := blake3.Sum256(data) // BLAKE3\n",
+		"crypt.py": "# Шифрование: rc4\n",
+	})
+	matches, err := scan.Scan([]string{dir},
+		scan.WithPattern(regexp.MustCompile(`(?i)\bblake3\b`)),
+		scan.WithPattern(regexp.MustCompile(`\p{Cyrillic}+ование`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.File+" "+m.Algorithm+" "+m.Severity)
+	}
+	// The built-in patterns still apply alongside the added ones
+	want := []string{"crypt.py ШИФРОВАНИЕ ok", "crypt.py RC4 weak", "hash.go BLAKE3 ok"}
+	if !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	dir := writeTree(t, map[string]string{"ok.go": "// AES\n", "broken.ipynb": "{\"cells\": [MD5"})
+	matches, err := scan.Scan([]string{dir})
+	if err == nil || !strings.Contains(err.Error(), "broken.ipynb") {
+		t.Errorf("Scan error = %v, want broken.ipynb reported", err)
+	}
+	if want := []string{"ok.go:1 AES"}; !sameList(found(matches), want) {
+		t.Errorf("found %q, want %q", found(matches), want)
+	}
+}
+
+func TestScanRemovesClones(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := writeTree(t, map[string]string{"a.go": "// MD5\n"})
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "commit"}} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	matches, err := scan.Scan([]string{"file://" + repo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.go:1 MD5"}; !sameList(found(matches), want) {
+		t.Errorf("found %q, want %q", found(matches), want)
+	}
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Errorf("%d entries left in the temporary directory, want the clone removed", len(left))
+	}
+}
+
+func TestScanErrorHandler(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore":   "[\n",
+		"ok.go":        "// AES\n",
+		"broken.ipynb": "{\"cells\": [MD5",
+	})
+	var errs, warnings []string
+	_, err := scan.Scan([]string{dir}, scan.WithErrorHandler(func(path string, err error) {
+		var warning *scan.Warning
+		if errors.As(err, &warning) {
+			warnings = append(warnings, path)
+		} else {
+			errs = append(errs, path)
+		}
+	}))
+	if err == nil {
+		t.Error("Scan error = nil, want broken.ipynb reported")
+	}
+	if want := []string{filepath.Join(dir, "broken.ipynb")}; !sameList(errs, want) {
+		t.Errorf("errors for %q, want %q", errs, want)
+	}
+	if want := []string{filepath.Join(dir, ".gitignore")}; !sameList(warnings, want) {
+		t.Errorf("warnings for %q, want %q", warnings, want)
+	}
+}
+
+func TestScanIsSilent(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		".gitignore":   "[\n",
+		"broken.ipynb": "{\"cells\": [MD5",
+	})
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	saved := os.Stderr
+	defer func() { os.Stderr = saved }()
+	os.Stderr = stderr
+
+	_, err = scan.Scan([]string{dir, filepath.Join(dir, "missing")})
+	if err == nil || !strings.Contains(err.Error(), "broken.ipynb") || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Scan error = %v, want both errors returned", err)
+	}
+	os.Stderr = saved
+	if data, _ := os.ReadFile(stderr.Name()); len(data) > 0 {
+		t.Errorf("Scan wrote to stderr:\n%s", data)
+	}
+}
+
+// rot13Detector reports ROT13, which no built-in pattern knows, wherever a
+// line mentions it.
+type rot13Detector struct{}
+
+func (rot13Detector) Detect(line string) []scan.Finding {
+	i := strings.Index(strings.ToLower(line), "rot13")
+	if i < 0 {
+		return nil
+	}
+	return []scan.Finding{{Algorithm: "ROT13", Severity: "weak", Start: i, End: i + len("rot13")}}
+}
+
+func TestScanWithDetector(t *testing.T) {
+	dir := writeTree(t, map[string]string{"obfuscate.py": "# rot13 then MD5\n"})
+	matches, err := scan.Scan([]string{dir}, scan.WithDetector(rot13Detector{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range matches {
+		got = append(got, fmt.Sprintf("%s:%d:%d %s %s", m.File, m.Line, m.Col, m.Algorithm, m.Severity))
+	}
+	want := []string{"obfuscate.py:1:3 ROT13 weak", "obfuscate.py:1:14 MD5 weak"}
+	if !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+func TestListDetectors(t *testing.T) {
+	infos := scan.ListDetectors(
+		scan.WithPattern(regexp.MustCompile(`(?i)\bblake3\b`)),
+		scan.WithDetector(rot13Detector{}))
+	var got []string
+	for _, info := range infos {
+		got = append(got, info.Name+" "+info.Severity)
+	}
+	want := []string{"algorithms varies", `(?i)\bblake3\b varies`, "scan_test.rot13Detector varies"}
+	if !sameList(got, want) {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,725 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Match is a single algorithm occurrence found in a scanned file.
+type Match struct {
+	Algorithm   string
+	Severity    string // "ok", "deprecated" or "weak"
+	Category    string // What the algorithm does, see categoryOf
+	QuantumRisk string // "vulnerable", "resistant" or "n-a"
+	Root        string // Directory argument the file was found under, an archive argument followed by archiveSeparator, a git URL, or empty for -tar
+	File        string // Path relative to Root
+	Line, Col   int    // 1-based
+	Context     string // The trimmed source line, recorded with -context
+	Usage       string // "non-security" when the line suggests a checksum or cache key, see nonSecurityUse
+	Source      string // "doc" for documentation files, see docExtensions, or "code"
+	Author      string // Author of the last commit to the line, with -git-blame
+	Commit      string // Hash of that commit, which introduced the line as it stands
+	Date        string // Author date of that commit, in RFC 3339 format
+
+	contextCol  int    // 0-based rune offset of the match within Context
+	fingerprint string // Identity across runs, with -baseline or -write-baseline
+}
+
+// Path returns the file path of the match as it should be displayed.
+func (m Match) Path() string {
+	switch {
+	case m.Root == "":
+		return m.File
+	case strings.HasSuffix(m.Root, archiveSeparator):
+		return m.Root + m.File
+	case strings.Contains(m.Root, "://"):
+		return m.Root + "/" + m.File // A git URL, which filepath.Join would mangle
+	}
+	return filepath.Join(m.Root, m.File)
+}
+
+// scanner holds the options and accumulated results for a single run.
+type scanner struct {
+	skipLinesOver int       // Lines of more characters than this are not matched (0 disables)
+	lineWindow    int       // Lines joined when continued by string concatenation (1 disables), see lineWindow
+	matches       []Match   // Every algorithm occurrence, sorted by sortMatches once the scan is done
+	linesSkipped  int       // Lines skipped because of skipLinesOver
+	dedupContent  bool      // Scan files with the same content once, see queueDistinct
+	held          []fileJob // With dedupContent, the files queued so far, guarded by mu
+	duplicates    int       // Files not scanned for having the content of another, see queueDistinct
+	filesScanned  int
+
+	detectors        []Detector            // Run on every scanned line in order, see Detector
+	allow            []allowRule           // Reviewed matches to drop, loaded with -allow
+	ignoreAlgos      []string              // Upper-case algorithm names dropped with -ignore-algo
+	nonSecurityHints []string              // Lower-case words marking hash matches as non-security, see nonSecurityUse
+	hideNonSecurity  bool                  // Drop the matches nonSecurityUse marks instead of down-ranking them
+	extensions       map[string]bool       // Extensions selected for scanning
+	allExtensions    bool                  // Skip the extension check entirely
+	noGitIgnore      bool                  // Do not load or apply the ignoreFiles
+	ignoreFiles      []string              // Names of the ignore files read in every directory, see loadIgnoreFiles
+	followSymlinks   bool                  // Walk into symlinked directories
+	maxSize          byteSize              // Files larger than this are skipped (0 disables)
+	verbose          bool                  // Log directories, files and skip decisions to stderr
+	skipComments     bool                  // Ignore matches inside comments, see commentSyntaxes
+	fileTimeout      time.Duration         // Files taking longer than this to scan are skipped (0 disables)
+	decompress       bool                  // Scan the decompressed content of .gz files
+	scanBinaries     bool                  // Scan the printable strings of binary files instead of skipping them
+	sniffBytes       int                   // Leading bytes of a file isBinaryContent judges it by
+	contextWidth     int                   // Characters of context shown per match (0 for the whole line)
+	fips             bool                  // Report algorithms that are not FIPS 140-2 approved
+	listFiles        bool                  // Print the files that pass the filters instead of scanning them
+	stats            map[string]*fileStats // Files and lines scanned per extension, with -stats
+	pathStyle        string                // How reported paths are rendered, see displayPath
+	baseline         map[string]int        // Fingerprints of the matches in the -baseline file, counted down as they are found
+	writeBaseline    bool                  // Record every match in baselineEntries
+	baselineEntries  []baselineEntry       // Matches written to the -baseline file with -write-baseline
+	gitBlame         bool                  // Attribute matches to the last commit of their line, see attachBlame
+	since            string                // Only walk the files changed since this git ref, see changedSince
+	skipDocs         bool                  // Skip files with docExtensions
+	allOccurrences   bool                  // Report every occurrence of an algorithm on a line rather than the first
+	detectKeyFiles   bool                  // Report the files with keyFileExtensions, see isKeyFile
+	showClean        bool                  // Record the files scanned without any match in clean
+	clean            []string              // Display paths of the files scanned without any match, with -show-clean
+	maxDepth         int                   // Directories deeper than this below a root are not entered (-1 for any depth)
+	withContext      bool                  // Record the matched line for each match
+	hints            bool                  // Report a remediation for each weak algorithm, see remediationOf
+	languageOf       map[string]string     // Extensions declared with -lang, mapped to the extension whose rules they follow
+	excludes         stringList            // Globs of paths to skip, see matchGlob
+	includes         stringList            // If set, only files matching one of these globs are scanned
+	forceIncludeDirs stringList            // Globs of directories scanned despite ignore files and extensions, see forceIncluded
+
+	workers        int                   // Number of goroutines scanning files
+	parallelRoots  int                   // Number of roots walked at once, see scanRoots
+	threadsPerFile int                   // Goroutines matching the lines of a file of parallelFileSize or more, see chunkMatcher
+	byRoot         map[string]*rootStats // Files scanned per root, keyed by rootKey, when several roots are scanned
+	jobs           chan fileJob          // Files queued by the directory walk
+	wg             sync.WaitGroup        // Tracks running workers
+
+	memBudget byteSize   // Bytes of files scanned at once across workers (0 disables), see reserve
+	memUsed   int64      // Bytes of the files being scanned, guarded by memMu
+	memMu     sync.Mutex // Guards memUsed
+	memFree   *sync.Cond // Signaled on memMu when a file is done
+	mu        sync.Mutex // Guards the accumulated results above and failed
+	failed    bool       // An operational error left the scan incomplete
+
+	onError func(path string, err error) // Receives the errors and warnings of errorf, warnf and fileError, with mu held; nil discards them
+
+	scanErrors []scanError // Files and directories that could not be scanned in full, guarded by mu
+	clones     []string    // Temporary clones of the git URLs scanned, guarded by mu, see scanRemote
+
+	head, tail  int         // List only this many of the most or least severe matches in the text report (0 for all), see shownMatches
+	maxFindings int         // Stop once this many matches at or above -severity-min are found (0 disables)
+	findings    int         // Matches counted towards maxFindings so far
+	stopped     atomic.Bool // maxFindings was reached; no further files are scanned
+
+	progress     bool          // Keep a count of scanned files on stderr, see showProgress
+	queued       atomic.Int64  // Files queued so far
+	scanned      atomic.Int64  // Files taken off the queue so far
+	progressStop chan struct{} // Closed by stopWorkers to end showProgress
+	progressDone chan struct{} // Closed by showProgress once its line is cleared
+
+	stream    *json.Encoder   // With -format jsonl, matches are written here per file instead of kept
+	streamMin int             // -severity-min level applied to streamed matches and to the -max-findings count
+	streamed  map[string]bool // Severities of every streamed match, for -fail-on
+	written   map[string]int  // Matches streamed at or above streamMin, per severity, for summaryLine
+	streamErr error           // First error writing to stream
+}
+
+// errorf handles an operational error with the file or directory displayed
+// as path, formatted as by fmt.Errorf: it is passed to onError, and the scan
+// is recorded as incomplete.
+func (s *scanner) errorf(path, format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	if s.onError != nil {
+		s.onError(path, fmt.Errorf(format, args...))
+	}
+}
+
+// warnf passes a problem with the file or directory displayed as path that
+// did not keep anything from being scanned, formatted as by fmt.Errorf, to
+// onError as a *Warning.
+func (s *scanner) warnf(path, format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.onError != nil {
+		s.onError(path, &Warning{fmt.Errorf(format, args...)})
+	}
+}
+
+// verbosef writes a diagnostic line to stderr when -verbose is set.
+func (s *scanner) verbosef(format string, args ...any) {
+	if !s.verbose {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// fileJob is a file queued for scanning by the worker pool.
+type fileJob struct {
+	path, root, name string
+	size             int64 // Bytes on disk, counted against -mem-budget
+}
+
+// startWorkers launches the worker pool that scans files queued by scanDir.
+func (s *scanner) startWorkers() {
+	if s.workers < 1 || s.listFiles {
+		s.workers = 1 // A single worker keeps -list-files in walk order
+	}
+	s.jobs = make(chan fileJob, s.workers)
+	s.memFree = sync.NewCond(&s.memMu)
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for job := range s.jobs {
+				if s.listFiles {
+					fmt.Println(Match{Root: job.root, File: job.name}.Path())
+					continue
+				}
+				if s.stopped.Load() {
+					continue // Drain the queue once -max-findings is reached
+				}
+				release := s.reserve(job.size)
+				if err := s.processFile(job.path, job.root, job.name, job.size); err != nil {
+					s.fileError(Match{Root: job.root, File: job.name}.Path(), err)
+				}
+				release()
+				s.scanned.Add(1)
+			}
+		}()
+	}
+	if s.progress {
+		s.progressStop = make(chan struct{})
+		s.progressDone = make(chan struct{})
+		go s.showProgress()
+	}
+}
+
+// reserve waits until n bytes of -mem-budget are free and takes them,
+// returning the function that gives them back. A file larger than the whole
+// budget takes all of it, so it waits for the files being scanned and is
+// then scanned alone.
+func (s *scanner) reserve(n int64) func() {
+	if s.memBudget <= 0 {
+		return func() {}
+	}
+	n = min(n, int64(s.memBudget))
+	s.memMu.Lock()
+	for s.memUsed+n > int64(s.memBudget) {
+		s.memFree.Wait()
+	}
+	s.memUsed += n
+	s.memMu.Unlock()
+	return func() {
+		s.memMu.Lock()
+		s.memUsed -= n
+		s.memMu.Unlock()
+		s.memFree.Broadcast()
+	}
+}
+
+// queue hands a file to the worker pool. With -dedup-content, files are
+// held back until the walk is done, see queueDistinct.
+func (s *scanner) queue(job fileJob) {
+	s.queued.Add(1)
+	if s.dedupContent {
+		s.mu.Lock()
+		s.held = append(s.held, job)
+		s.mu.Unlock()
+		return
+	}
+	s.jobs <- job
+}
+
+// stopWorkers waits for all queued files to be scanned.
+func (s *scanner) stopWorkers() {
+	if s.dedupContent {
+		s.queueDistinct()
+	}
+	close(s.jobs)
+	s.wg.Wait()
+	if s.progress {
+		close(s.progressStop)
+		<-s.progressDone
+	}
+}
+
+// clearLine returns the cursor to the start of the terminal line and erases
+// it.
+const clearLine = "\r\033[K"
+
+// showProgress rewrites a single stderr line with the number of files
+// scanned out of those queued so far until stopWorkers is called, then
+// erases it. The walk runs ahead of the workers, so the total grows while
+// the tree is still being walked.
+func (s *scanner) showProgress() {
+	defer close(s.progressDone)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			fmt.Fprintf(os.Stderr, "%sScanned %d of %d files", clearLine, s.scanned.Load(), s.queued.Load())
+			s.mu.Unlock()
+		case <-s.progressStop:
+			s.mu.Lock()
+			fmt.Fprint(os.Stderr, clearLine)
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// stringList is a flag.Value collecting every use of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// byteSize is a flag.Value holding a size in bytes, accepting an optional
+// K, M or G suffix (powers of 1024) such as "5M".
+type byteSize int64
+
+var sizeSuffixes = map[string]int64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30}
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	value = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	digits := strings.TrimRight(value, "KMG")
+	multiplier, ok := sizeSuffixes[value[len(digits):]]
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if !ok || err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", value)
+	}
+	*b = byteSize(n * multiplier)
+	return nil
+}
+
+// processFile scans the file at path, recording matches as name under root.
+// It returns an error if the file could not be opened or read to the end;
+// matches found before a read error are still recorded.
+func (s *scanner) processFile(path, root, name string, size int64) error {
+	s.verbosef("Scanning %s\n", path)
+	// The walk only queues regular files, but one may have been replaced
+	// since, and opening a FIFO blocks until something writes to it
+	if info, err := os.Stat(path); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("opening file: %s is not a regular file", path)
+	}
+	file, err := s.openFile(path)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	switch {
+	case s.scanBinaries:
+		reader = binaryStrings(bufio.NewReaderSize(file, max(s.sniffBytes, 4096)), s.sniffBytes)
+	case s.isKeyFile(path) && (!s.hasValidExtension(s.innerName(path)) || s.isBinary(path)):
+		reader = strings.NewReader("") // Only the file's presence is reported
+	}
+	ctx, cancel := s.fileContext()
+	defer cancel()
+	return s.scanReader(ctx, reader, root, name, size)
+}
+
+// capFindings returns matches cut off where the -max-findings count is
+// reached and, once it is, stops the scan. Matches below -severity-min do not
+// count and are kept, so that -fail-on still sees them. It is called with
+// s.mu held, once per scanned file.
+func (s *scanner) capFindings(matches []Match) []Match {
+	if s.maxFindings <= 0 {
+		return matches
+	}
+	kept := matches[:0]
+	for _, m := range matches {
+		if severityLevels[m.Severity] >= s.streamMin {
+			if s.findings == s.maxFindings {
+				s.stopped.Store(true)
+				continue
+			}
+			s.findings++
+		}
+		kept = append(kept, m)
+	}
+	if s.findings == s.maxFindings {
+		s.stopped.Store(true)
+	}
+	return kept
+}
+
+// fileError handles an error that kept the file or directory displayed as
+// path from being scanned in full: it is passed to onError and kept for the
+// errors section of the report, or the error returned by Scan, and the scan
+// is recorded as incomplete.
+func (s *scanner) fileError(path string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.scanErrors = append(s.scanErrors, scanError{Path: path, Error: err.Error()})
+	if s.onError != nil {
+		s.onError(path, err)
+	}
+}
+
+// scanError is a file or directory that could not be scanned in full, as
+// listed in the errors section of the report.
+type scanError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// fileContext returns the context bounding the scan of a single file, which
+// expires after -file-timeout when that is set.
+func (s *scanner) fileContext() (context.Context, context.CancelFunc) {
+	if s.fileTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), s.fileTimeout)
+}
+
+// maxLineLength is the longest line scanReader can handle, well above the
+// 64KB bufio.Scanner default that minified and bundled files exceed.
+const maxLineLength = 16 * 1024 * 1024
+
+// scanReader matches every line read from r against the algorithm patterns,
+// recording matches as name under root. The extension of name selects which
+// matches are meaningful for the file type. ctx is checked between lines; if
+// it expires, the file is abandoned and none of its matches are recorded.
+// UTF-16 content with a byte order mark is decoded first, and only the code
+// cells of a Jupyter notebook are scanned, see notebookCode. Lines marked
+// with a dumpvars:ignore comment report nothing, see suppresses. The returned
+// error describes a failure to read r; a timeout is handed to fileError.
+func (s *scanner) scanReader(ctx context.Context, r io.Reader, root, name string, size int64) error {
+	ext := strings.ToLower(filepath.Ext(s.innerName(name)))
+	lang := s.languageExt(ext)
+	detectors := s.detectorsFor(lang)
+	r = utf16Text(bufio.NewReader(contextReader{ctx, r}))
+	if lang == ".ipynb" {
+		code, err := notebookCode(r)
+		if err != nil {
+			return fmt.Errorf("reading notebook %s: %w", Match{Root: root, File: name}.Path(), err)
+		}
+		r = code
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	styleFile := styleExtensions[lang]
+	source := "code"
+	if docExtensions[lang] {
+		source = "doc"
+	}
+	inComment := false
+	syntax, hasSyntax := commentSyntaxes[lang]
+	skipComments := s.skipComments && hasSyntax
+	inBlockComment := false
+	var suppressed map[int]bool // Lines marked with a dumpvars:ignore comment
+
+	// Results are collected locally and merged once the file is done, so
+	// that concurrent workers only contend for the lock once per file
+	var matches []Match
+	linesSkipped := 0
+	lines := 0
+	if s.isKeyFile(name) {
+		// The file itself is the finding, reported at its first line
+		m := Match{
+			Algorithm:   keyFileAlgorithm,
+			Severity:    "weak",
+			Category:    categoryOf(keyFileAlgorithm),
+			QuantumRisk: quantumRiskOf(keyFileAlgorithm),
+			Root:        root,
+			File:        name,
+			Line:        1,
+			Col:         1,
+			Source:      source,
+		}
+		if s.baseline != nil || s.writeBaseline {
+			m.fingerprint = fingerprint(keyFileAlgorithm, name, "")
+		}
+		if !s.allowed(m, "") && !s.ignoredAlgorithm(m.Algorithm) {
+			matches = append(matches, m)
+		}
+	}
+	defer func() {
+		if ctx.Err() != nil {
+			// An error like any other file that could not be scanned in full
+			path := Match{Root: root, File: name}.Path()
+			s.fileError(path, fmt.Errorf("scanning %s: not done within -file-timeout %s", path, s.fileTimeout))
+			return
+		}
+		// Line numbers of decompressed files and notebooks do not refer to
+		// lines of the file in git
+		if s.gitBlame && len(matches) > 0 && !s.decompressed(name) && lang != ".ipynb" && !strings.HasSuffix(root, archiveSeparator) && !isGitURL(root) {
+			s.attachBlame(Match{Root: root, File: name}.Path(), matches)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.filesScanned++
+		s.linesSkipped += linesSkipped
+		if stats := s.byRoot[rootKey(root, name)]; stats != nil {
+			stats.FilesScanned++
+		}
+		if s.stats != nil {
+			key := ext
+			if key == "" {
+				key = noExtension
+			}
+			if s.stats[key] == nil {
+				s.stats[key] = &fileStats{}
+			}
+			s.stats[key].Files++
+			s.stats[key].Lines += lines
+		}
+		if s.showClean && len(matches) == 0 {
+			s.clean = append(s.clean, Match{Root: root, File: name}.Path())
+		}
+		matches = s.applyBaseline(matches)
+		matches = s.capFindings(matches)
+		if s.stream != nil {
+			s.streamMatches(matches)
+		} else {
+			s.matches = append(s.matches, matches...)
+		}
+	}()
+
+	lm := &lineMatcher{s: s, detectors: detectors, root: root, name: name, source: source, styleFile: styleFile}
+	// Lines are read, and the comment state carried from one to the next
+	// tracked, in order; only matching them is shared out in a large file
+	var window *lineWindow
+	if s.lineWindow > 1 {
+		window = &lineWindow{size: s.lineWindow}
+	}
+	var chunks *chunkMatcher
+	if s.threadsPerFile > 1 && size >= parallelFileSize {
+		chunks = newChunkMatcher(lm, s.threadsPerFile)
+	}
+	for lineNo := 1; ctx.Err() == nil && scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		lines++
+		// A line of more than skipLinesOver bytes may still hold fewer
+		// characters, so runes are only counted for the long lines
+		if s.skipLinesOver > 0 && len(line) > s.skipLinesOver && utf8.RuneCountInString(line) > s.skipLinesOver {
+			// Most likely an embedded data blob (base64, minified data), not code
+			linesSkipped++
+			if window != nil {
+				window.reset()
+			}
+			continue
+		}
+
+		// In styling files short tokens only count inside comments and url(...)
+		var regions [][2]int
+		if styleFile {
+			regions, inComment = styleRegions(line, inComment)
+		}
+		// With -skip-comments nothing inside a comment counts, so comments
+		// are blanked out before the detectors see the line
+		visible := line
+		var comments [][2]int
+		if hasSyntax {
+			comments, inBlockComment = commentRegions(line, syntax, inBlockComment)
+		}
+		if skipComments {
+			visible = blank(line, comments)
+		}
+		if suppresses(line, comments, hasSyntax) {
+			if suppressed == nil {
+				suppressed = make(map[int]bool)
+			}
+			suppressed[lineNo] = true
+		}
+		l := scanLine{no: lineNo, text: line, visible: visible, regions: regions}
+		if window != nil {
+			l.joined = window.add(lineNo, line, visible)
+		}
+		if chunks != nil {
+			chunks.add(l)
+			continue
+		}
+		matches = lm.match(l, matches)
+	}
+	if chunks != nil {
+		matches = chunks.wait(matches)
+	}
+	matches = unsuppressed(matches, suppressed)
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("reading %s: %w", Match{Root: root, File: name}.Path(), err)
+	}
+	return nil
+}
+
+// contextReader reads from r until ctx is done, so that -file-timeout also
+// stops a scan waiting on a slow read or inside a long line, not only between
+// lines.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// scanLine is a line of a file ready for the detectors: its text, the text
+// the detectors see, and in styling files the regions where short tokens
+// count.
+type scanLine struct {
+	no      int
+	text    string
+	visible string
+	regions [][2]int
+	joined  []joinSpan // With -line-window, the lines this one continues and itself, see lineWindow
+}
+
+// lineMatcher runs the detectors over the lines of one file. It only reads
+// its fields, so several goroutines can match lines of the same file.
+type lineMatcher struct {
+	s                  *scanner
+	detectors          []Detector
+	root, name, source string
+	styleFile          bool
+}
+
+// match appends the matches found on l to matches.
+func (lm *lineMatcher) match(l scanLine, matches []Match) []Match {
+	relevant := func(token string, loc []int) bool {
+		return !lm.styleFile || !shortTokenAlgorithms[strings.ToUpper(token)] || within(loc, l.regions)
+	}
+
+	first := len(matches)
+
+	for _, f := range detectAll(lm.detectors, l.visible) {
+		loc := []int{f.Start, f.End}
+		if base, _ := splitAlgorithm(f.Algorithm); !relevant(base, loc) {
+			continue
+		}
+		if f.Severity == "" {
+			f.Severity = severityOf(f.Algorithm)
+		}
+		matches = append(matches, lm.newMatch(f.Algorithm, f.Severity, l.no, l.text, f.Start))
+	}
+
+	// Usage hints, -allow rules and -ignore-algo are applied once the
+	// line is complete, when modes have been attached and the reported
+	// names are final
+	lineMatches := lm.filter(matches[first:], l.text)
+	if len(l.joined) > 0 {
+		return lm.crossLine(l.joined, matches[:first], lineMatches)
+	}
+	return append(matches[:first], lineMatches...)
+}
+
+// newMatch returns the match of alg found at byte offset col of line lineNo,
+// whose text is text.
+func (lm *lineMatcher) newMatch(alg, severity string, lineNo int, text string, col int) Match {
+	m := Match{
+		Algorithm:   alg,
+		Severity:    severity,
+		Category:    categoryOf(alg),
+		QuantumRisk: quantumRiskOf(alg),
+		Root:        lm.root,
+		File:        lm.name,
+		Line:        lineNo,
+		Col:         col + 1,
+		Source:      lm.source,
+	}
+	if lm.s.baseline != nil || lm.s.writeBaseline {
+		m.fingerprint = fingerprint(alg, lm.name, text)
+	}
+	if lm.s.withContext {
+		indented := strings.TrimLeftFunc(text, unicode.IsSpace)
+		m.Context = strings.TrimSpace(indented)
+		m.contextCol = utf8.RuneCountInString(text[len(text)-len(indented) : col])
+	}
+	return m
+}
+
+// filter applies the usage hints, -allow rules and -ignore-algo to the
+// matches found on text, then keeps one match per algorithm, at its first
+// column, unless -all-occurrences is given. It reuses the backing array of
+// matches.
+func (lm *lineMatcher) filter(matches []Match, text string) []Match {
+	s := lm.s
+	if len(matches) > 0 && (len(s.nonSecurityHints) > 0 || len(s.allow) > 0 || len(s.ignoreAlgos) > 0) {
+		nonSecurity := s.nonSecurityUse(text)
+		kept := matches[:0]
+		for _, m := range matches {
+			if nonSecurity && m.Category == "hash" {
+				if s.hideNonSecurity {
+					continue
+				}
+				m.Usage = "non-security"
+				m.Severity = lowerSeverity(m.Severity)
+			}
+			if !s.allowed(m, text) && !s.ignoredAlgorithm(m.Algorithm) {
+				kept = append(kept, m)
+			}
+		}
+		matches = kept
+	}
+
+	// A line mentioning an algorithm several times yields one match for it
+	if len(matches) > 1 && !s.allOccurrences {
+		kept := matches[:0]
+		index := make(map[string]int)
+		for _, m := range matches {
+			if i, ok := index[m.Algorithm]; ok {
+				if m.Line < kept[i].Line || m.Line == kept[i].Line && m.Col < kept[i].Col {
+					kept[i] = m
+				}
+				continue
+			}
+			index[m.Algorithm] = len(kept)
+			kept = append(kept, m)
+		}
+		matches = kept
+	}
+	return matches
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// overlaps reports whether the span loc overlaps any of spans.
+func overlaps(loc []int, spans [][]int) bool {
+	for _, span := range spans {
+		if loc[0] < span[1] && span[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
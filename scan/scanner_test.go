@@ -0,0 +1,226 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// newTestScanner returns a scanner set up as Main sets it up when no flag is
+// given, but with a single worker so that tests do not depend on the number
+// of CPUs.
+func newTestScanner() *scanner {
+	s := newScanner()
+	s.workers = 1
+	s.parallelRoots = 1
+	return s
+}
+
+// writeTree creates files, keyed by slash-separated path, under a new
+// temporary directory and returns the directory.
+func writeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// scan runs s over roots as Main does and returns the matches, sorted.
+func scan(t *testing.T, s *scanner, roots ...string) []Match {
+	t.Helper()
+	s.startWorkers()
+	s.scanRoots(roots)
+	s.stopWorkers()
+	sortMatches(s.matches)
+	return s.matches
+}
+
+// found returns "file:line algorithm" for each of matches, with file
+// relative to its scan root.
+func found(matches []Match) []string {
+	var list []string
+	for _, m := range matches {
+		list = append(list, fmt.Sprintf("%s:%d %s", m.File, m.Line, m.Algorithm))
+	}
+	return list
+}
+
+// sameList reports whether got and want hold the same strings in the same
+// order.
+func sameList(got, want []string) bool {
+	return strings.Join(got, "\n") == strings.Join(want, "\n")
+}
+
+func TestControlCharactersInSource(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"formfeed.go": "package cipher\n\f\n// Encrypt uses AES.\nfunc Encrypt() {}\n",
+		"vtab.go":     "package cipher\n\v\n// Hash uses MD5.\n\vfunc Hash() {}\n",
+	})
+	for _, name := range []string{"formfeed.go", "vtab.go"} {
+		if isBinaryFile(filepath.Join(dir, name), defaultSniffBytes) {
+			t.Errorf("isBinaryFile(%s) = true, want false", name)
+		}
+	}
+	got := found(scan(t, newTestScanner(), dir))
+	want := []string{"formfeed.go:3 AES", "vtab.go:3 MD5"}
+	if !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+// BenchmarkScan scans a generated tree of source files, a few of which use
+// weak algorithms, with the default number of workers.
+func BenchmarkScan(b *testing.B) {
+	files := make(map[string]string)
+	var body strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&body, "\tsum += value(%d) // plain code without algorithms\n", i)
+	}
+	for i := 0; i < 500; i++ {
+		content := "package gen\n\nfunc run() {\n" + body.String()
+		if i%10 == 0 {
+			content += "\th := md5.New()\n\tc, _ := aes.NewCipher(key)\n"
+		}
+		files[fmt.Sprintf("pkg%d/file%d.go", i%20, i)] = content + "}\n"
+	}
+	dir := writeTree(b, files)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := newTestScanner()
+		s.workers = runtime.NumCPU()
+		s.startWorkers()
+		s.scanRoots([]string{dir})
+		s.stopWorkers()
+		if len(s.matches) == 0 {
+			b.Fatal("no matches")
+		}
+	}
+}
+
+func TestFailOnECB(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"mode.go": "package cipher\n\nvar mode = \"ECB\"\n",
+	})
+	if code, out := runArgs(t, "-fail-on", "weak", dir); code != exitFindings {
+		t.Errorf("exit %d for an ECB-only file with -fail-on weak, want %d; output:\n%s", code, exitFindings, out)
+	}
+	if code, out := runArgs(t, dir); code != exitOK {
+		t.Errorf("exit %d without -fail-on, want %d; output:\n%s", code, exitOK, out)
+	}
+}
+
+func TestLongLine(t *testing.T) {
+	line := strings.Repeat("x", 200<<10) + ` cipher = "AES"`
+	dir := writeTree(t, map[string]string{"long.js": line + "\n"})
+
+	got := found(scan(t, newTestScanner(), dir))
+	if want := []string{"long.js:1 AES"}; !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+
+	s := newTestScanner()
+	s.skipLinesOver = 100 << 10
+	if got := found(scan(t, s, dir)); len(got) != 0 {
+		t.Errorf("found %q with -skip-lines-over, want nothing", got)
+	}
+	if s.linesSkipped != 1 {
+		t.Errorf("linesSkipped = %d, want 1", s.linesSkipped)
+	}
+}
+
+func TestFileTimeoutStopsRead(t *testing.T) {
+	s := newTestScanner()
+	s.fileTimeout = 50 * time.Millisecond
+	ctx, cancel := s.fileContext()
+	defer cancel()
+	// A line that never ends, read slowly, as from a stalled network mount
+	done := make(chan error)
+	go func() { done <- s.scanReader(ctx, slowReader{}, "", "slow.go", 0) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("scanReader = %v, want the timeout only warned about", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("scanReader ignored -file-timeout inside a line")
+	}
+	if len(s.scanErrors) != 1 || !s.failed {
+		t.Errorf("scanErrors = %v, failed = %v, want the timeout as an error", s.scanErrors, s.failed)
+	}
+}
+
+// slowReader yields an endless line a byte at a time.
+type slowReader struct{}
+
+func (slowReader) Read(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	p[0] = 'x'
+	return 1, nil
+}
+
+func TestUTF16LittleEndian(t *testing.T) {
+	text := "' Settings.cs\r\nvar alg = Aes.Create(); // AES\r\nhash = MD5\r\n"
+	content := []byte{0xff, 0xfe}
+	for _, r := range utf16.Encode([]rune(text)) {
+		content = append(content, byte(r), byte(r>>8))
+	}
+	dir := writeTree(t, map[string]string{"Settings.cs": string(content)})
+	if isBinaryFile(filepath.Join(dir, "Settings.cs"), defaultSniffBytes) {
+		t.Error("isBinaryFile = true for UTF-16LE text")
+	}
+	got := found(scan(t, newTestScanner(), dir))
+	if want := []string{"Settings.cs:2 AES", "Settings.cs:3 MD5"}; !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
+
+func TestNonSecurityHints(t *testing.T) {
+	s := newTestScanner()
+	s.nonSecurityHints = strings.Split(defaultNonSecurityHints, ",")
+	tests := map[string]bool{
+		`sum := md5.Sum(data) // checksum`:        true,
+		`md5Checksum := md5.Sum(data)`:            true,
+		`key := cache_key(md5(url))`:              true,
+		`h := crc32.ChecksumIEEE(b)`:              true,
+		`w.Header().Set("ETag", md5hex)`:          true,
+		`fingerprints = [sha1(c) for c in certs]`: true,
+		`digest := md5.Sum(password)`:             false,
+		`cachet := md5(secret)`:                   false,
+		`processCredential(md5(pin))`:             false,
+	}
+	for line, want := range tests {
+		if got := s.nonSecurityUse(line); got != want {
+			t.Errorf("nonSecurityUse(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestInlineSuppression(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go": "h := md5.New() // dumpvars:ignore cache key\n" +
+			"msg := \"dumpvars:ignore\"; h = md5.New()\n" +
+			"h = md5.New() // dumpvars:ignored\n" +
+			"/*\n * md5.New() dumpvars:ignore\n */\n",
+		"b.py":  "# dumpvars:ignore\nh = hashlib.md5(data)\n",
+		"c.txt": "md5.New() dumpvars:ignore\n",
+	})
+	s := newTestScanner()
+	got := found(scan(t, s, root))
+	want := []string{"a.go:2 MD5", "a.go:3 MD5", "b.py:2 MD5"}
+	if !sameList(got, want) {
+		t.Errorf("found %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package scan
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// inGitTree reports whether dir lies in a git working tree.
+func inGitTree(dir string) bool {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// changedSince returns the files of the git working tree holding dir that
+// changed between ref and HEAD and still exist, and every directory on the
+// way to them, as slash-separated paths relative to dir ("." for dir
+// itself). Files outside dir are left out.
+func changedSince(dir, ref string) (map[string]bool, error) {
+	out, err := exec.Command("git", "-C", dir, "diff", "--name-only", "--relative", "--diff-filter=d", "-z", ref, "HEAD", "--").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	changed := map[string]bool{".": true}
+	for _, name := range bytes.Split(out, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		for p := string(name); p != "."; p = path.Dir(p) {
+			changed[p] = true
+		}
+	}
+	return changed, nil
+}
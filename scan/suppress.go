@@ -0,0 +1,137 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// suppressMarker, written in a comment, keeps the findings on its line out of
+// the report:
+//
+//	sum := md5.Sum(body) // dumpvars:ignore ETag, not a signature
+//
+// Whatever follows the marker is the reason, for readers of the code.
+const suppressMarker = "dumpvars:ignore"
+
+// suppresses reports whether line holds suppressMarker inside one of its
+// comments, the regions commentRegions found. In files whose comment syntax
+// is unknown the marker counts anywhere on the line.
+func suppresses(line string, comments [][2]int, hasSyntax bool) bool {
+	for i := 0; ; {
+		j := strings.Index(line[i:], suppressMarker)
+		if j == -1 {
+			return false
+		}
+		loc := []int{i + j, i + j + len(suppressMarker)}
+		i = loc[1]
+		// dumpvars:ignored or dumpvars:ignore_all is not the marker
+		if r, _ := utf8.DecodeRuneInString(line[i:]); isWordRune(r) || r == '_' || r == '-' {
+			continue
+		}
+		if !hasSyntax || within(loc, comments) {
+			return true
+		}
+	}
+}
+
+// unsuppressed drops the matches found on the lines in suppressed. It reuses
+// the backing array of matches.
+func unsuppressed(matches []Match, suppressed map[int]bool) []Match {
+	if len(suppressed) == 0 {
+		return matches
+	}
+	kept := matches[:0]
+	for _, m := range matches {
+		if !suppressed[m.Line] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// suppressInline marks the line of m with a suppressMarker comment giving
+// reason, during triage. Only files scanned from disk as they are can be
+// edited: not archive members, clones of git URLs, decompressed files or
+// notebooks, whose lines are not the lines of a file on disk.
+func (s *scanner) suppressInline(m Match, reason string) error {
+	switch {
+	case strings.HasSuffix(m.Root, archiveSeparator):
+		return fmt.Errorf("%s is inside an archive", m.Path())
+	case isGitURL(m.Root):
+		return fmt.Errorf("%s is in a clone of %s", m.File, m.Root)
+	case s.decompressed(m.File):
+		return fmt.Errorf("%s is compressed", m.Path())
+	}
+	lang := s.languageExt(strings.ToLower(filepath.Ext(m.File)))
+	syntax, ok := commentSyntaxes[lang]
+	if lang == ".ipynb" || !ok {
+		return fmt.Errorf("no comment syntax is known for %s", m.Path())
+	}
+	return addSuppression(m.Path(), syntax, m.Line, m.Context, reason)
+}
+
+// addSuppression appends a line comment in syntax holding suppressMarker and
+// reason to line lineNo of the file at path, whose trimmed text must still be
+// text. The line ending and the file's permissions are kept, and the file is
+// replaced in one rename by replaceFile. A line that already holds the
+// marker is left as it is; one that ends inside a string or continues on the
+// next line cannot take a comment, and is refused.
+func addSuppression(path string, syntax commentSyntax, lineNo int, text, reason string) error {
+	// The comment goes into the file a symlink points to, not over the link
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	content := string(data)
+	inBlock := false
+	start := 0
+	for no := 1; no < lineNo; no++ {
+		end := strings.IndexByte(content[start:], '\n')
+		if end == -1 {
+			return fmt.Errorf("%s has fewer than %d lines", path, lineNo)
+		}
+		_, inBlock = commentRegions(strings.TrimSuffix(content[start:start+end], "\r"), syntax, inBlock)
+		start += end + 1
+	}
+	end := strings.IndexByte(content[start:], '\n')
+	if end == -1 {
+		end = len(content) - start
+	}
+	end += start
+	line := strings.TrimSuffix(content[start:end], "\r")
+	if strings.TrimSpace(line) != text {
+		return fmt.Errorf("line %d of %s changed since the scan", lineNo, path)
+	}
+	comments, _, quote := lineComments(line, syntax, inBlock)
+	if suppresses(line, comments, true) {
+		return nil
+	}
+	code := strings.TrimRight(line, " \t")
+	if quote != 0 {
+		return fmt.Errorf("line %d of %s ends inside a string", lineNo, path)
+	}
+	if strings.HasSuffix(code, "\\") {
+		return fmt.Errorf("line %d of %s continues on the next line", lineNo, path)
+	}
+	comment := syntax.line[0] + " " + suppressMarker
+	if reason = strings.TrimSpace(reason); reason != "" {
+		comment += " " + reason
+	}
+	if code != "" {
+		comment = " " + comment
+	}
+	lineEnd := start + len(line)
+	edited := content[:start] + code + comment + content[lineEnd:]
+	return replaceFile(path, []byte(edited), info.Mode().Perm())
+}
@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package scan
+
+import (
+	"errors"
+	"os"
+)
+
+// terminalWidth returns 0 as terminal sizes are not queried on this
+// platform; callers fall back to a fixed width.
+func terminalWidth(f *os.File) int {
+	return 0
+}
+
+// terminalSize returns zeros as terminal sizes are not queried on this
+// platform.
+func terminalSize(f *os.File) (rows, cols int) {
+	return 0, 0
+}
+
+// makeRaw fails as raw terminal mode is not supported on this platform.
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
@@ -0,0 +1,56 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package scan
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalWidth returns the number of columns of the terminal f refers to,
+// or 0 if it cannot be determined.
+func terminalWidth(f *os.File) int {
+	_, cols := terminalSize(f)
+	return cols
+}
+
+// terminalSize returns the number of rows and columns of the terminal f
+// refers to, or zeros if they cannot be determined.
+func terminalSize(f *os.File) (rows, cols int) {
+	var size struct {
+		rows, cols, x, y uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0, 0
+	}
+	return int(size.rows), int(size.cols)
+}
+
+// makeRaw switches the terminal f refers to into raw mode, where keys are
+// read one at a time as typed, without echo or signals, and returns a
+// function restoring the previous mode.
+func makeRaw(f *os.File) (func(), error) {
+	var old syscall.Termios
+	if err := termios(f, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+	raw := old
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := termios(f, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() { termios(f, ioctlSetTermios, &old) }, nil
+}
+
+func termios(f *os.File, request uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
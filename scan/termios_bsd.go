@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || netbsd || openbsd
+
+package scan
+
+import "syscall"
+
+// Requests reading and setting terminal attributes, see makeRaw.
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,9 @@
+package scan
+
+import "syscall"
+
+// Requests reading and setting terminal attributes, see makeRaw.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,172 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// triageAnswer is what the user decided for a finding during triage.
+type triageAnswer int
+
+const (
+	triageSkip      triageAnswer = iota // Leave the finding reported
+	triageAccept                        // Add the finding to the baseline
+	triageInline                        // Mark the finding's line with a dumpvars:ignore comment
+	triageAcceptAll                     // Add this and every remaining finding to the baseline
+	triageQuit                          // Stop, keeping the decisions made so far
+)
+
+// triagePrompt lists the keys triageKey answers to, besides Enter, which
+// skips, and Ctrl-C and Ctrl-D, which quit.
+const triagePrompt = "[b]aseline, [i]gnore inline, [s]kip, [a]ll remaining to the baseline, [q]uit: "
+
+// triageKey maps a key typed during triage to its answer, or reports false
+// for keys that answer nothing.
+func triageKey(key string) (triageAnswer, bool) {
+	switch key {
+	case "b":
+		return triageAccept, true
+	case "i":
+		return triageInline, true
+	case "s", "\r":
+		return triageSkip, true
+	case "a":
+		return triageAcceptAll, true
+	case "q", "\003", "\004": // Ctrl-C and Ctrl-D, as signals are off in raw mode
+		return triageQuit, true
+	}
+	return triageSkip, false
+}
+
+// readTriageKey waits for a key triageKey answers to, with the terminal in
+// raw mode only for as long as it waits. The end of input quits.
+func readTriageKey() (triageAnswer, error) {
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return triageQuit, err
+	}
+	defer restore()
+	keys := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(keys)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return triageQuit, err
+		}
+		if n == 0 {
+			return triageQuit, nil
+		}
+		if answer, ok := triageKey(string(keys[:n])); ok {
+			return answer, nil
+		}
+	}
+}
+
+// readReason reads the reason for an inline suppression, a line typed with
+// the terminal in its normal mode.
+func readReason() (string, error) {
+	fmt.Print("Reason: ")
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 0 || b[0] == '\n' {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return string(line), err
+		}
+		line = append(line, b[0])
+	}
+}
+
+// triage walks the user through the matches on the terminal, the findings
+// the baseline at path does not hold yet. Each one can be added to the
+// baseline or, when inline is set, ignored with a dumpvars:ignore comment
+// that suppressInline writes into the file right away. The baseline is
+// rewritten once, at the end, by writeBaseline; quitting early keeps the
+// decisions made so far.
+func (s *scanner) triage(path string, inline bool) error {
+	entries, err := readBaseline(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(s.matches) == 0 {
+		fmt.Println("No findings outside the baseline")
+		return nil
+	}
+
+	accepted, ignored := 0, 0
+	acceptAll := false
+	commented := make(map[string]bool) // path:line of the lines given a comment
+triage:
+	for i, m := range s.matches {
+		at := fmt.Sprintf("%s:%d", m.Path(), m.Line)
+		if commented[at] {
+			// The comment added for an earlier finding covers the whole line
+			ignored++
+			continue
+		}
+		answer := triageAccept
+		if !acceptAll {
+			fmt.Printf("[%d/%d] %s %s at %s:%d%s\n", i+1, len(s.matches), m.Severity, m.Algorithm, at, m.Col, blameNote(m))
+			if m.Context != "" {
+				fmt.Printf("        %s\n", excerpt(m.Context, m.contextCol, s.contextWidth))
+			}
+			for {
+				fmt.Print(triagePrompt)
+				answer, err = readTriageKey()
+				fmt.Println()
+				if err != nil {
+					return err
+				}
+				if answer != triageInline {
+					break
+				}
+				if !inline {
+					fmt.Println("Cannot ignore inline: files in a -tar stream cannot be edited")
+					continue
+				}
+				reason, err := readReason()
+				if err != nil {
+					return err
+				}
+				if err := s.suppressInline(m, reason); err != nil {
+					fmt.Printf("Cannot ignore inline: %s\n", err)
+					continue
+				}
+				break
+			}
+		}
+		switch answer {
+		case triageQuit:
+			break triage
+		case triageInline:
+			commented[at] = true
+			ignored++
+			continue
+		case triageAcceptAll:
+			acceptAll = true
+		case triageSkip:
+			continue
+		}
+		entries = append(entries, baselineEntry{Fingerprint: m.fingerprint, Algorithm: m.Algorithm, File: m.File, Line: m.Line})
+		accepted++
+	}
+
+	if accepted == 0 {
+		fmt.Println("Baseline unchanged")
+	} else {
+		if err := writeBaseline(path, entries); err != nil {
+			return err
+		}
+		fmt.Printf("Added %s to %s\n", plural(accepted, "finding"), path)
+	}
+	if ignored > 0 {
+		fmt.Printf("Ignored %s with %s comments\n", plural(ignored, "finding"), suppressMarker)
+	}
+	fmt.Printf("%d left to fix\n", len(s.matches)-accepted-ignored)
+	return nil
+}
@@ -0,0 +1,146 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTriageKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		answer triageAnswer
+		ok     bool
+	}{
+		{"b", triageAccept, true},
+		{"i", triageInline, true},
+		{"s", triageSkip, true},
+		{"\r", triageSkip, true},
+		{"a", triageAcceptAll, true},
+		{"q", triageQuit, true},
+		{"\003", triageQuit, true},
+		{"\004", triageQuit, true},
+		{"y", triageSkip, false},
+		{"x", triageSkip, false},
+		{"\x1b[A", triageSkip, false},
+	}
+	for _, test := range tests {
+		answer, ok := triageKey(test.key)
+		if answer != test.answer || ok != test.ok {
+			t.Errorf("triageKey(%q) = %v, %v, want %v, %v", test.key, answer, ok, test.answer, test.ok)
+		}
+	}
+}
+
+func TestWriteBaselineReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "baseline.json")
+	if err := os.WriteFile(name, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries := []baselineEntry{
+		{Fingerprint: "b", Algorithm: "MD5", File: "b.go", Line: 2},
+		{Fingerprint: "a", Algorithm: "AES", File: "a.go", Line: 1},
+	}
+	if err := writeBaseline(name, entries); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readBaseline(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Fingerprint != "a" || got[1].Fingerprint != "b" {
+		t.Errorf("readBaseline = %+v, want the two entries sorted", got)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("directory holds %d files, want only the baseline", len(files))
+	}
+}
+
+func TestAddSuppression(t *testing.T) {
+	tests := []struct {
+		name, content string
+		line          int
+		text, reason  string
+		want          string // "" when the file must be left as it is
+		err           bool
+	}{
+		{"a.go", "package a\n\nh := md5.New()  \nx := 1\n", 3, "h := md5.New()", "cache key",
+			"package a\n\nh := md5.New() // dumpvars:ignore cache key\nx := 1\n", false},
+		{"a.py", "h = hashlib.md5()", 1, "h = hashlib.md5()", "",
+			"h = hashlib.md5() # dumpvars:ignore", false},
+		{"a.sql", "SELECT md5(x)\r\nFROM t\r\n", 1, "SELECT md5(x)", "checksum",
+			"SELECT md5(x) -- dumpvars:ignore checksum\r\nFROM t\r\n", false},
+		{"a.go", "/*\n  md5 notes\n*/\n", 2, "md5 notes", "doc",
+			"/*\n  md5 notes // dumpvars:ignore doc\n*/\n", false},
+		{"a.go", "h := md5.New() // dumpvars:ignore\n", 1, "h := md5.New() // dumpvars:ignore", "again", "", false},
+		{"a.go", "s := `md5\n`\n", 1, "s := `md5", "", "", true},
+		{"a.sh", "openssl md5 \\\n  file\n", 1, "openssl md5 \\", "", "", true},
+		{"a.go", "h := sha256.New()\n", 1, "h := md5.New()", "", "", true},
+		{"a.go", "h := md5.New()\n", 2, "h := md5.New()", "", "", true},
+	}
+	for _, test := range tests {
+		name := filepath.Join(t.TempDir(), test.name)
+		if err := os.WriteFile(name, []byte(test.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		err := addSuppression(name, commentSyntaxes[filepath.Ext(name)], test.line, test.text, test.reason)
+		if (err != nil) != test.err {
+			t.Errorf("addSuppression(%q, %d) error = %v, want error %v", test.content, test.line, err, test.err)
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := test.want
+		if want == "" {
+			want = test.content
+		}
+		if string(data) != want {
+			t.Errorf("addSuppression(%q, %d) wrote %q, want %q", test.content, test.line, data, want)
+		}
+		if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("addSuppression(%q, %d) left mode %v, %v, want 0600", test.content, test.line, info.Mode(), err)
+		}
+		if entries, _ := os.ReadDir(filepath.Dir(name)); len(entries) != 1 {
+			t.Errorf("addSuppression(%q, %d) left %d files behind", test.content, test.line, len(entries)-1)
+		}
+	}
+}
+
+func TestSuppressInlineRefuses(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "md5\n"})
+	s := newTestScanner()
+	for _, m := range []Match{
+		{Root: "app.tar" + archiveSeparator, File: "a.go", Line: 1, Context: "md5"},
+		{Root: "https://example.com/repo.git", File: "a.go", Line: 1, Context: "md5"},
+		{Root: root, File: "a.go.gz", Line: 1, Context: "md5"},
+		{Root: root, File: "a.ipynb", Line: 1, Context: "md5"},
+		{Root: root, File: "a.txt", Line: 1, Context: "md5"},
+	} {
+		if err := s.suppressInline(m, ""); err == nil {
+			t.Errorf("suppressInline(%s) succeeded, want an error", m.Path())
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "md5\n" {
+		t.Errorf("a.txt changed to %q", data)
+	}
+}
+
+func TestSuppressInlineHidesFinding(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "h := md5.New()\nh = md5.New()\n"})
+	s := newTestScanner()
+	s.withContext = true
+	m := scan(t, s, root)[0]
+	if err := s.suppressInline(m, "test fixture"); err != nil {
+		t.Fatal(err)
+	}
+	got := found(scan(t, newTestScanner(), root))
+	if want := []string{"a.go:2 MD5"}; !sameList(got, want) {
+		t.Errorf("after suppressInline found %q, want %q", got, want)
+	}
+}
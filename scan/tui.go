@@ -0,0 +1,261 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// tuiRow is a line of the -tui listing: a severity or file heading, or a
+// match.
+type tuiRow struct {
+	text     string
+	severity string // Set on severity headings, to color them
+	match    *Match // Nil for headings
+}
+
+// tui is the state of the interactive browser started by -tui. Matches are
+// listed by severity, most severe first, then by file; the cursor only ever
+// rests on a match.
+type tui struct {
+	s       *scanner
+	rows    []tuiRow
+	cursor  int    // Index in rows of the selected match, or -1 if none is listed
+	top     int    // Index in rows of the first row on screen
+	filter  string // Case-insensitive substring the listed algorithms contain
+	editing bool   // Keys are typed into filter
+	detail  bool   // Show the context of the selected match
+}
+
+// severityColors are the ANSI colors of the severity headings.
+var severityColors = map[string]string{
+	"weak":       "\033[1;31m",
+	"deprecated": "\033[1;33m",
+	"ok":         "\033[1;32m",
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runTUI lets the user browse the matches on the terminal until they quit.
+// It takes over the whole screen and restores it on return.
+func (s *scanner) runTUI() error {
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	fmt.Print("\033[?1049h\033[?25l") // Alternate screen, hidden cursor
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	t := &tui{s: s}
+	t.build()
+	keys := make([]byte, 64)
+	for {
+		t.draw()
+		n, err := os.Stdin.Read(keys)
+		if err != nil {
+			return err
+		}
+		if !t.handle(string(keys[:n])) {
+			return nil
+		}
+	}
+}
+
+// build lists the matches passing the filter, keeping the selected match
+// selected if it still is listed.
+func (t *tui) build() {
+	var selected *Match
+	if t.cursor >= 0 && t.cursor < len(t.rows) {
+		selected = t.rows[t.cursor].match
+	}
+
+	bySeverity := make(map[string][]Match)
+	filter := strings.ToLower(t.filter)
+	for _, m := range t.s.matches {
+		if strings.Contains(strings.ToLower(m.Algorithm), filter) {
+			bySeverity[m.Severity] = append(bySeverity[m.Severity], m)
+		}
+	}
+	severities := make([]string, 0, len(bySeverity))
+	for severity := range bySeverity {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		return severityLevels[severities[i]] > severityLevels[severities[j]]
+	})
+
+	t.rows = t.rows[:0]
+	t.cursor = -1
+	for _, severity := range severities {
+		matches := bySeverity[severity]
+		t.rows = append(t.rows, tuiRow{text: fmt.Sprintf("%s: %s", severity, plural(len(matches), "finding")), severity: severity})
+		files, byPath := groupByFile(matches)
+		for _, file := range files {
+			t.rows = append(t.rows, tuiRow{text: fmt.Sprintf("  %s (%d)", file, len(byPath[file]))})
+			for i := range byPath[file] {
+				m := &byPath[file][i]
+				t.rows = append(t.rows, tuiRow{
+					text:  fmt.Sprintf("    %d:%d %s [%s]%s%s", m.Line, m.Col, m.Algorithm, m.Category, usageNote(*m), sourceNote(*m)),
+					match: m,
+				})
+				if t.cursor < 0 || selected != nil && *m == *selected {
+					t.cursor = len(t.rows) - 1
+				}
+			}
+		}
+	}
+}
+
+// move selects the match n matches below the current one, or above it for a
+// negative n, stopping at the first and last match.
+func (t *tui) move(n int) {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for i := t.cursor + step; n > 0 && i >= 0 && i < len(t.rows); i += step {
+		if t.rows[i].match != nil {
+			t.cursor = i
+			n--
+		}
+	}
+}
+
+// handle acts on the keys read in one go and reports whether to carry on.
+func (t *tui) handle(keys string) bool {
+	if t.editing {
+		if len(keys) > 1 && keys[0] == '\033' {
+			return true // Arrows and other special keys do nothing while typing
+		}
+		for _, r := range keys {
+			switch {
+			case r == '\r' || r == '\033':
+				t.editing = false
+			case r == 127 || r == '\b':
+				if runes := []rune(t.filter); len(runes) > 0 {
+					t.filter = string(runes[:len(runes)-1])
+				}
+			case r >= ' ':
+				t.filter += string(r)
+			}
+		}
+		t.build()
+		return true
+	}
+
+	_, page := t.layout()
+	switch keys {
+	case "q", "\003": // Ctrl-C, as signals are off in raw mode
+		return false
+	case "\033[A", "k":
+		t.move(-1)
+	case "\033[B", "j":
+		t.move(1)
+	case "\033[5~":
+		t.move(-page)
+	case "\033[6~", " ":
+		t.move(page)
+	case "g", "\033[H":
+		t.move(-len(t.rows))
+	case "G", "\033[F":
+		t.move(len(t.rows))
+	case "\r":
+		t.detail = !t.detail
+	case "/":
+		t.editing = true
+	case "\033":
+		t.filter, t.detail = "", false
+		t.build()
+	}
+	return true
+}
+
+// layout returns the size of the terminal and the number of rows left for
+// the listing.
+func (t *tui) layout() (cols, list int) {
+	rows, cols := terminalSize(os.Stdout)
+	if rows <= 0 || cols <= 0 {
+		rows, cols = 24, 80
+	}
+	list = rows - 2 // Title and status lines
+	if t.detail {
+		list -= 5
+	}
+	return cols, max(list, 1)
+}
+
+// draw repaints the whole screen.
+func (t *tui) draw() {
+	cols, list := t.layout()
+	if t.cursor >= 0 {
+		// Keep the cursor on screen, with its headings where they fit
+		if t.cursor < t.top {
+			t.top = t.cursor
+			for t.top > 0 && t.rows[t.top-1].match == nil && t.cursor-t.top+1 < list {
+				t.top--
+			}
+		}
+		if t.cursor >= t.top+list {
+			t.top = t.cursor - list + 1
+		}
+	}
+
+	var b strings.Builder
+	line := func(prefix, text string) {
+		b.WriteString(prefix + excerpt(text, 0, cols) + "\033[0m\033[K\n")
+	}
+	b.WriteString("\033[H")
+	title := fmt.Sprintf("dumpvars: %s", plural(len(t.s.matches), "finding"))
+	if t.filter != "" {
+		title += fmt.Sprintf(", algorithm contains %q", t.filter)
+	}
+	line("\033[1m", title)
+
+	for i := t.top; i < t.top+list; i++ {
+		switch {
+		case i >= len(t.rows):
+			switch {
+			case i == 0 && t.filter != "":
+				line("", "No finding matches the filter.")
+			case i == 0:
+				line("", "No algorithms found.")
+			default:
+				line("", "")
+			}
+		case i == t.cursor:
+			line("\033[7m", t.rows[i].text)
+		case t.rows[i].severity != "":
+			line(severityColors[t.rows[i].severity], t.rows[i].text)
+		default:
+			line("", t.rows[i].text)
+		}
+	}
+
+	if t.detail {
+		line("", strings.Repeat("─", cols))
+		if t.cursor >= 0 {
+			m := t.rows[t.cursor].match
+			line("\033[1m", fmt.Sprintf("%s:%d:%d", m.Path(), m.Line, m.Col))
+			line("", fmt.Sprintf("%s [%s, %s, quantum %s]%s%s", m.Algorithm, m.Severity, m.Category, m.QuantumRisk, usageNote(*m), blameNote(*m)))
+			line("", excerpt(m.Context, m.contextCol, cols))
+			line("", t.s.remediation(m.Algorithm))
+		} else {
+			for i := 0; i < 4; i++ {
+				line("", "")
+			}
+		}
+	}
+
+	if t.editing {
+		b.WriteString("Filter by algorithm: " + t.filter + "\033[K")
+	} else {
+		b.WriteString("\033[2m↑/↓ move  PgUp/PgDn page  Enter context  / filter  Esc clear  q quit\033[0m\033[K")
+	}
+	os.Stdout.WriteString(b.String())
+}
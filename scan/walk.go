@@ -0,0 +1,395 @@
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// tooLarge reports whether a file of size bytes exceeds -max-size.
+func (s *scanner) tooLarge(name string, size int64) bool {
+	if s.maxSize <= 0 || size <= int64(s.maxSize) {
+		return false
+	}
+	s.verbosef("Skipping %s: %d bytes exceeds -max-size\n", name, size)
+	return true
+}
+
+// excluded reports whether relPath matches any -exclude glob.
+func (s *scanner) excluded(relPath string) bool {
+	for _, pattern := range s.excludes {
+		if matchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether a file at relPath passes the -include allowlist.
+// Directories are not subject to it so that the walk can reach matching
+// files below them.
+func (s *scanner) included(relPath string) bool {
+	if len(s.includes) == 0 {
+		return true
+	}
+	for _, pattern := range s.includes {
+		if matchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// forceIncluded reports whether relPath is below a directory matching a
+// -force-include-dir glob or, for a directory, matches one itself.
+func (s *scanner) forceIncluded(relPath string, isDir bool) bool {
+	if len(s.forceIncludeDirs) == 0 {
+		return false
+	}
+	dir := relPath
+	if !isDir {
+		dir = path.Dir(relPath)
+	}
+	for ; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		for _, pattern := range s.forceIncludeDirs {
+			if matchGlob(pattern, dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// leadsToForced reports whether a -force-include-dir glob could match a
+// directory below the directory at relPath, which must then be entered even
+// if an ignore file excludes it.
+func (s *scanner) leadsToForced(relPath string) bool {
+	for _, pattern := range s.forceIncludeDirs {
+		if strings.Contains(pattern, "/") && matchPrefix(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(relPath, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the slash-separated relPath matches pattern.
+// Segments use path.Match syntax and "**" matches any number of directories.
+// A pattern without a "/" is matched against every path segment instead, so
+// "vendor" or "*.min.js" apply at any depth.
+func matchGlob(pattern, relPath string) bool {
+	segments := strings.Split(relPath, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, segment := range segments {
+			if ok, _ := path.Match(pattern, segment); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), segments)
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// matchPrefix reports whether segments match the leading segments of
+// pattern, so that paths below them could match the whole pattern.
+func matchPrefix(pattern, segments []string) bool {
+	for ; len(segments) > 0; pattern, segments = pattern[1:], segments[1:] {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tooDeep reports whether the directory at relPath lies beyond -depth. The
+// scan root is at depth 0 and each directory below it adds one level.
+func (s *scanner) tooDeep(relPath string) bool {
+	if s.maxDepth < 0 || relPath == "." {
+		return false
+	}
+	if depth := strings.Count(relPath, "/") + 1; depth > s.maxDepth {
+		s.verbosef("Skipping %s: deeper than -depth\n", relPath)
+		return true
+	}
+	return false
+}
+
+// scanDir walks dir and scans every file that passes the extension, binary
+// and .gitignore filters. Matches are attributed to dir as given, while the
+// walk itself uses absolute paths so that the process working directory is
+// never changed.
+//
+// Symlinked files are scanned and filtered by the extension of their target.
+// Symlinked directories are only entered with -follow-symlinks. To protect
+// against cycles, the real path of every directory entered is recorded and a
+// directory reached a second time, through a link or otherwise, is skipped.
+//
+// If dir is a file rather than a directory, that file alone is scanned,
+// subject to the same filters except .gitignore. If it is a git URL, a
+// shallow clone of the repository is scanned, see scanRemote.
+func (s *scanner) scanDir(dir string) {
+	if isGitURL(dir) {
+		s.scanRemote(dir)
+		return
+	}
+	root, err := filepath.Abs(dir)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		s.errorf(dir, "resolving directory: %w", err)
+		return
+	}
+	if info, err := os.Stat(root); err == nil && info.Mode().IsRegular() {
+		if archiveFormat(root) != "" {
+			s.scanArchive(dir, root)
+			return
+		}
+		s.scanSingleFile(dir, root, info.Size())
+		return
+	}
+	s.walkRoot(dir, root, s.displayPath(dir))
+}
+
+// walkRoot queues the files of the directory named dir on the command line,
+// whose resolved path is root, to be reported under display.
+func (s *scanner) walkRoot(dir, root, display string) {
+	s.addRoot(display)
+
+	// Ignore files and .dumpvarsignore rules are loaded per directory as the
+	// walk enters it
+	ignorePatterns := make(ignoreRules)
+	visited := make(map[string]bool)
+
+	// With -since, only changed files and the directories holding them are
+	// walked
+	var changed map[string]bool
+	if s.since != "" {
+		var err error
+		if isGitURL(dir) {
+			// The shallow clone of scanRemote has no history to compare with
+			s.warnf(dir, "-since does not apply to %s, cloned without history; scanning all of it", dir)
+		} else if !inGitTree(root) {
+			s.warnf(dir, "%s is not in a git working tree; scanning all of it despite -since", dir)
+		} else if changed, err = changedSince(root, s.since); err != nil {
+			s.errorf(dir, "listing files changed since %s in %s: %w", s.since, dir, err)
+			return
+		}
+	}
+
+	// walk scans the tree at start, whose path relative to root is startRel
+	var walk func(start, startRel string) error
+	walk = func(start, startRel string) error {
+		return filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
+			if s.stopped.Load() {
+				return filepath.SkipAll
+			}
+			walkErr := err
+			relPath, err := filepath.Rel(start, path)
+			if err != nil {
+				return err
+			}
+			relPath = filepath.ToSlash(filepath.Join(startRel, relPath))
+			if walkErr != nil {
+				// An unreadable directory is left out rather than ending the walk
+				s.fileError(Match{Root: display, File: relPath}.Path(), fmt.Errorf("walking: %w", walkErr))
+				return nil
+			}
+
+			if changed != nil && !changed[relPath] {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				s.verbosef("Skipping %s: unchanged since %s\n", path, s.since)
+				return nil
+			}
+
+			target := path
+			if info.Mode()&os.ModeSymlink != 0 {
+				if target, err = filepath.EvalSymlinks(path); err != nil {
+					return nil // Dangling link
+				}
+				if info, err = os.Stat(target); err != nil {
+					return nil
+				}
+				if info.IsDir() {
+					if !s.followSymlinks || visited[target] || s.tooDeep(relPath) || s.shouldIgnore(target, relPath, ignorePatterns, true) {
+						return nil
+					}
+					return walk(target, relPath)
+				}
+			}
+
+			if info.IsDir() {
+				if s.tooDeep(relPath) || s.shouldIgnore(path, relPath, ignorePatterns, true) {
+					// Skip directories based on ignore file rules
+					return filepath.SkipDir
+				}
+				if realPath, err := filepath.EvalSymlinks(path); err == nil {
+					if visited[realPath] {
+						return filepath.SkipDir
+					}
+					visited[realPath] = true
+				}
+				s.verbosef("Entering %s\n", path)
+				ignorePatterns[relPath] = s.loadIgnoreRules(path)
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				// Opening a FIFO or device would block, or never end
+				s.verbosef("Skipping %s: not a regular file\n", path)
+				return nil
+			}
+			if s.shouldIgnore(target, relPath, ignorePatterns, false) || s.tooLarge(path, info.Size()) {
+				return nil
+			}
+			s.queue(fileJob{path: target, root: display, name: relPath, size: info.Size()})
+			return nil
+		})
+	}
+	if err := walk(root, "."); err != nil {
+		s.errorf(dir, "walking directory: %w", err)
+	}
+}
+
+// displayPath renders a scan root or file named on the command line for the
+// report according to -path-style: unchanged for "relative", made absolute for
+// "absolute", and relative to the working directory for "cwd". Paths within a
+// root are appended to the rendered root, see Match.Path.
+func (s *scanner) displayPath(name string) string {
+	if s.pathStyle != "absolute" && s.pathStyle != "cwd" {
+		return name
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return name
+	}
+	if s.pathStyle == "cwd" {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil {
+				return rel
+			}
+		}
+	}
+	return abs
+}
+
+// scanSingleFile scans the file named on the command line as name, whose
+// resolved path is path. Unlike files found by a walk, a file skipped by a
+// filter is reported with a warning, since it was asked for explicitly.
+func (s *scanner) scanSingleFile(name, path string, size int64) {
+	relPath := filepath.ToSlash(name)
+	reason := s.fileSkipReason(path, relPath)
+	if reason == "" && s.maxSize > 0 && size > int64(s.maxSize) {
+		reason = fmt.Sprintf("%d bytes exceeds -max-size", size)
+	}
+	if reason != "" {
+		s.warnf(name, "skipping %s: %s", name, reason)
+		return
+	}
+	key := filepath.ToSlash(s.displayPath(name))
+	s.addRoot(key)
+	s.queue(fileJob{path: path, name: key, size: size})
+}
+
+// scanFileList scans the files named one per line in r, such as the output
+// of git diff --name-only. The extension and binary checks apply, but
+// .gitignore does not since there is no scan root to resolve it against.
+func (s *scanner) scanFileList(r io.Reader) error {
+	lines := bufio.NewScanner(r)
+	for !s.stopped.Load() && lines.Scan() {
+		name := strings.TrimSpace(lines.Text())
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			s.fileError(name, fmt.Errorf("opening file: %w", err))
+			continue
+		}
+		if info.IsDir() {
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			s.warnf(name, "skipping %s: not a regular file", name)
+			continue
+		}
+		if reason := s.fileSkipReason(name, filepath.ToSlash(name)); reason != "" {
+			s.verbosef("Skipping %s: %s\n", name, reason)
+			continue
+		}
+		if s.tooLarge(name, info.Size()) {
+			continue
+		}
+		s.queue(fileJob{path: name, name: filepath.ToSlash(s.displayPath(name)), size: info.Size()})
+	}
+	return lines.Err()
+}
+
+// fileSkipReason applies the checks shared by every way of listing files on
+// disk: the -exclude and -include globs, the extension and binary content.
+func (s *scanner) fileSkipReason(path, relPath string) string {
+	if s.excluded(relPath) {
+		return "-exclude"
+	}
+
+	if !s.included(relPath) {
+		return "no -include match"
+	}
+
+	// Key files are reported whatever their extension and content
+	if s.isKeyFile(path) {
+		return ""
+	}
+
+	// With -scan-binaries, binary files are scanned whatever their
+	// extension, since executables and libraries rarely have a listed one
+	if s.scanBinaries && s.isBinary(path) {
+		return ""
+	}
+
+	// Check if the file extension is in the list of valid extensions;
+	// path is the link target for symlinks, so its extension is used
+	if !s.hasValidExtension(s.innerName(path)) && !s.forceIncluded(relPath, false) {
+		return "extension"
+	}
+
+	if s.skipDocs && isDoc(s.innerName(path)) {
+		return "documentation"
+	}
+
+	if !s.scanBinaries && s.isBinary(path) {
+		return "binary"
+	}
+	return ""
+}